@@ -0,0 +1,72 @@
+package sqlserver
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TableWithCollation is implemented by models whose character columns share a collation, SQL Server has no
+// table collation, so it's applied to the columns without a collate tag, which declares the collation of a column, e.g.
+//
+//	Code string `gorm:"size:32;collate:Latin1_General_CS_AS"`
+type TableWithCollation interface {
+	Collation() string
+}
+
+var collationRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// collationOf returns the collation of a character field, declared by its collate tag or the TableWithCollation model,
+// collations are names like Latin1_General_CS_AS, anything else is rejected, as it would be pasted into the DDL
+func collationOf(field *schema.Field) (collation string, err error) {
+	collation = strings.TrimSpace(field.TagSettings["COLLATE"])
+	if collation == "" && field.Schema != nil && field.Schema.ModelType != nil {
+		if table, ok := reflect.New(field.Schema.ModelType).Interface().(TableWithCollation); ok {
+			collation = strings.TrimSpace(table.Collation())
+		}
+	}
+	if collation != "" && !collationRegexp.MatchString(collation) {
+		return "", fmt.Errorf("invalid collation %s of column %s", collation, field.DBName)
+	}
+	return collation, nil
+}
+
+// isCharacterType reports whether the data type can have a collation
+func isCharacterType(dataType string) bool {
+	name, _ := parseDataType(dataType)
+	switch name {
+	case "char", "varchar", "nchar", "nvarchar", "text", "ntext", "character", "character varying",
+		"national character", "national character varying", "national char", "national char varying", "char varying":
+		return true
+	}
+	return false
+}
+
+// collationChanged reports whether the column's collation differs from the declared collation of the field,
+// columns of fields without collation keep their collation
+func collationChanged(field *schema.Field, columnType gorm.ColumnType) bool {
+	collation, err := collationOf(field)
+	info, ok := columnType.(*ColumnInfo)
+	if err != nil || collation == "" || !ok || info.Collation == "" {
+		return false
+	}
+	return !strings.EqualFold(collation, info.Collation)
+}
+
+// DatabaseCollation returns the default collation of the current database, which is the collation of
+// character columns without collation
+func (m Migrator) DatabaseCollation() (collation string, err error) {
+	err = m.DB.Raw("SELECT CONVERT(nvarchar(128), DATABASEPROPERTYEX(DB_NAME(), 'Collation'))").Scan(&collation).Error
+	return
+}
+
+// TempDBCollation returns the collation of tempdb, the server collation, which applies to the character columns of
+// temporary tables, comparing them with columns of another collation fails with a collation conflict (error 468)
+func (m Migrator) TempDBCollation() (collation string, err error) {
+	err = m.DB.Raw("SELECT CONVERT(nvarchar(128), DATABASEPROPERTYEX('tempdb', 'Collation'))").Scan(&collation).Error
+	return
+}
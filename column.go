@@ -0,0 +1,370 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// migratorColumnType is embedded by ColumnInfo, its type name would collide with the ColumnType method
+type migratorColumnType = migrator.ColumnType
+
+// ColumnInfo is a column type returned by ColumnInfos, it carries the SQL Server specific properties of the column
+type ColumnInfo struct {
+	migratorColumnType
+	// Computed reports whether the column is a computed column, ComputedDefinition is its expression
+	Computed           bool
+	ComputedDefinition string
+	Persisted          bool
+	// Collation is the collation of character columns
+	Collation string
+	// IdentitySeed and IdentityIncrement are valid for identity columns, IdentityCurrent once rows have been inserted
+	IdentitySeed      sql.NullInt64
+	IdentityIncrement sql.NullInt64
+	IdentityCurrent   sql.NullInt64
+}
+
+// MigratorColumnType returns the migrator.ColumnType embedded by the column info, as returned by ColumnTypes
+func (c *ColumnInfo) MigratorColumnType() migrator.ColumnType {
+	return c.migratorColumnType
+}
+
+// ColumnInfos returns the column types of value's table like ColumnTypes, with the SQL Server specific properties of
+// the columns
+func (m Migrator) ColumnInfos(value interface{}) ([]*ColumnInfo, error) {
+	columnTypes, err := m.ColumnTypes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	columnInfos := make([]*ColumnInfo, 0, len(columnTypes))
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		properties, err := m.columnPropertiesOf(stmt, "")
+		if err != nil {
+			return err
+		}
+
+		for _, columnType := range columnTypes {
+			columnInfo := properties[columnType.Name()]
+			if columnInfo == nil {
+				columnInfo = &ColumnInfo{}
+			}
+			columnInfo.migratorColumnType = columnType.(migrator.ColumnType)
+			columnInfos = append(columnInfos, columnInfo)
+		}
+		return nil
+	})
+	return columnInfos, err
+}
+
+// columnInfoOf returns the column info of the column type gorm passes to MigrateColumn, its SQL Server specific
+// properties are looked up unless it is a *ColumnInfo already
+func (m Migrator) columnInfoOf(value interface{}, columnType gorm.ColumnType) (columnInfo *ColumnInfo, err error) {
+	if columnInfo, ok := columnType.(*ColumnInfo); ok {
+		return columnInfo, nil
+	}
+
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		properties, err := m.columnPropertiesOf(stmt, columnType.Name())
+		if err != nil {
+			return err
+		}
+
+		if columnInfo = properties[columnType.Name()]; columnInfo == nil {
+			columnInfo = &ColumnInfo{}
+		}
+		if migratorColumnType, ok := columnType.(migrator.ColumnType); ok {
+			columnInfo.migratorColumnType = migratorColumnType
+		}
+		return nil
+	})
+	return columnInfo, err
+}
+
+// columnPropertiesOf queries the SQL Server specific properties of the columns of the statement's table, of all
+// columns if column is empty, the column infos are keyed by column name and don't carry the column types yet
+func (m Migrator) columnPropertiesOf(stmt *gorm.Statement, column string) (map[string]*ColumnInfo, error) {
+	query := strings.TrimSpace(`
+SELECT c.name, c.is_computed, cc.definition, cc.is_persisted, c.collation_name,
+	CAST(ic.seed_value AS bigint), CAST(ic.increment_value AS bigint), CAST(ic.last_value AS bigint)
+FROM sys.columns c
+LEFT JOIN sys.computed_columns cc ON cc.object_id = c.object_id AND cc.column_id = c.column_id
+LEFT JOIN sys.identity_columns ic ON ic.object_id = c.object_id AND ic.column_id = c.column_id
+WHERE c.object_id = OBJECT_ID(?)`)
+
+	queryParameters := []interface{}{m.fullTableNameOf(stmt)}
+	if column != "" {
+		query += " AND c.name = ?"
+		queryParameters = append(queryParameters, column)
+	}
+
+	rows, err := m.queryDB().Raw(query, queryParameters...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	properties := map[string]*ColumnInfo{}
+	for rows.Next() {
+		var (
+			name               string
+			computedDefinition sql.NullString
+			persistedValue     sql.NullBool
+			collationValue     sql.NullString
+			columnInfo         = &ColumnInfo{}
+			values             = []interface{}{
+				&name, &columnInfo.Computed, &computedDefinition, &persistedValue, &collationValue,
+				&columnInfo.IdentitySeed, &columnInfo.IdentityIncrement, &columnInfo.IdentityCurrent,
+			}
+		)
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		columnInfo.ComputedDefinition = computedDefinition.String
+		columnInfo.Persisted = persistedValue.Bool
+		columnInfo.Collation = collationValue.String
+		properties[name] = columnInfo
+	}
+	return properties, rows.Err()
+}
+
+// typeAliases are the equivalent names of SQL Server data types
+var typeAliases = map[string][]string{
+	"int":              {"integer"},
+	"integer":          {"int"},
+	"decimal":          {"numeric", "dec"},
+	"numeric":          {"decimal", "dec"},
+	"dec":              {"decimal", "numeric"},
+	"float":            {"double precision"},
+	"double precision": {"float"},
+	"rowversion":       {"timestamp"},
+	"timestamp":        {"rowversion"},
+	"char":             {"character"},
+	"character":        {"char"},
+	"varchar":          {"character varying", "char varying"},
+	"nchar":            {"national character", "national char"},
+	"nvarchar":         {"national character varying", "national char varying"},
+	"varbinary":        {"binary varying"},
+}
+
+// GetTypeAliases returns the equivalent names of the data type
+func (m Migrator) GetTypeAliases(databaseTypeName string) []string {
+	return typeAliases[strings.ToLower(databaseTypeName)]
+}
+
+var dataTypeRegexp = regexp.MustCompile(`^\s*([a-z][a-z0-9_ ]*?)\s*(?:\(\s*([^)]*?)\s*\))?\s*$`)
+
+// withoutIdentity strips the IDENTITY property from the data type, which ALTER COLUMN doesn't accept
+func withoutIdentity(dataType string) string {
+	if idx := strings.Index(strings.ToUpper(dataType), " IDENTITY"); idx >= 0 {
+		return strings.TrimSpace(dataType[:idx])
+	}
+	return dataType
+}
+
+// parseDataType splits a data type like nvarchar(MAX) or decimal(10, 2) into its lower cased name and arguments,
+// the IDENTITY property and COLLATE are ignored
+func parseDataType(dataType string) (name string, args []string) {
+	dataType = strings.ToLower(dataType)
+	for _, property := range []string{" identity", " collate "} {
+		if idx := strings.Index(dataType, property); idx >= 0 {
+			dataType = dataType[:idx]
+		}
+	}
+	matches := dataTypeRegexp.FindStringSubmatch(dataType)
+	if matches == nil {
+		return strings.TrimSpace(dataType), nil
+	}
+	if matches[2] != "" {
+		for _, arg := range strings.Split(matches[2], ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+	}
+	return matches[1], args
+}
+
+// gormColumnType is embedded by normalizedColumnType, its type name would collide with the ColumnType method
+type gormColumnType = gorm.ColumnType
+
+// normalizedColumnType is the column type gorm's MigrateColumn compares with the field, it reports the data type and
+// default value of the field if the column's are equivalent, e.g. nvarchar(MAX) for the length -1 or 0 for ((0)),
+// which gorm would compare literally
+type normalizedColumnType struct {
+	gormColumnType
+	dataType     string
+	defaultValue *sql.NullString
+}
+
+// DatabaseTypeName returns the full data type of the field
+func (c normalizedColumnType) DatabaseTypeName() string {
+	return c.dataType
+}
+
+// DefaultValue returns the default value of the field if it's equivalent to the default value of the column
+func (c normalizedColumnType) DefaultValue() (string, bool) {
+	if c.defaultValue != nil {
+		return c.defaultValue.String, c.defaultValue.Valid
+	}
+	return c.gormColumnType.DefaultValue()
+}
+
+// normalizeColumnType returns the column type that gorm's MigrateColumn compares with the field, the data type of the
+// column has to be unchanged, see dataTypeChanged
+func (m Migrator) normalizeColumnType(field *schema.Field, columnType gorm.ColumnType) gorm.ColumnType {
+	normalized := normalizedColumnType{
+		gormColumnType: columnType,
+		dataType:       strings.TrimSpace(strings.ToLower(m.DB.Migrator().FullDataTypeOf(field).SQL)),
+	}
+	if !m.defaultValueChanged(field, columnType) {
+		normalized.defaultValue = &sql.NullString{
+			String: field.DefaultValue,
+			Valid:  field.HasDefaultValue && (field.DefaultValueInterface != nil || !strings.EqualFold(field.DefaultValue, "NULL")),
+		}
+	}
+	return normalized
+}
+
+// dataTypeChanged compares the data type of the field with the type of the column, equivalent definitions like
+// nvarchar(MAX) and length -1 or float and float(53) are equal. The IDENTITY property is ignored.
+func (m Migrator) dataTypeChanged(field *schema.Field, columnType gorm.ColumnType) bool {
+	name, args := parseDataType(m.DataTypeOf(field))
+	realName := strings.ToLower(columnType.DatabaseTypeName())
+
+	// float(1) to float(24) are stored as real
+	if name == "float" && len(args) == 1 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n <= 24 {
+				name = "real"
+			}
+			args = nil
+		}
+	}
+
+	if name != realName {
+		sameType := false
+		for _, alias := range m.GetTypeAliases(realName) {
+			if alias == name {
+				sameType = true
+				break
+			}
+		}
+		if !sameType {
+			return true
+		}
+	}
+
+	switch realName {
+	case "char", "varchar", "nchar", "nvarchar", "binary", "varbinary":
+		length, ok := columnType.Length()
+		if !ok || len(args) == 0 {
+			return false
+		}
+		if args[0] == "max" {
+			return length != -1
+		}
+		expected, err := strconv.ParseInt(args[0], 10, 64)
+		return err == nil && expected != length
+	case "decimal", "numeric":
+		precision, scale, ok := columnType.DecimalSize()
+		if !ok {
+			return false
+		}
+		expectedPrecision, expectedScale := int64(18), int64(0)
+		if len(args) > 0 {
+			expectedPrecision, _ = strconv.ParseInt(args[0], 10, 64)
+		}
+		if len(args) > 1 {
+			expectedScale, _ = strconv.ParseInt(args[1], 10, 64)
+		}
+		return precision != expectedPrecision || scale != expectedScale
+	case "datetime2", "datetimeoffset", "time":
+		precision, _, ok := columnType.DecimalSize()
+		if !ok {
+			return false
+		}
+		expectedPrecision := int64(7)
+		if len(args) > 0 {
+			expectedPrecision, _ = strconv.ParseInt(args[0], 10, 64)
+		}
+		return precision != expectedPrecision
+	}
+	return false
+}
+
+// defaultValueChanged compares the default value of the field with the default constraint of the column
+func (m Migrator) defaultValueChanged(field *schema.Field, columnType gorm.ColumnType) bool {
+	expected, hasDefault := m.defaultValueOf(field)
+	if hasDefault && strings.EqualFold(expected, "NULL") {
+		hasDefault = false
+	}
+	current, hasCurrent := columnType.DefaultValue()
+	if hasCurrent && strings.EqualFold(normalizeDefaultValue(current), "null") {
+		hasCurrent = false
+	}
+
+	if !hasDefault || !hasCurrent {
+		return hasDefault != hasCurrent
+	}
+
+	expected, current = normalizeDefaultValue(expected), normalizeDefaultValue(current)
+	if field.GORMDataType == schema.Bool {
+		v1, err1 := strconv.ParseBool(expected)
+		v2, err2 := strconv.ParseBool(current)
+		if err1 == nil && err2 == nil {
+			return v1 != v2
+		}
+	}
+	if f1, err := strconv.ParseFloat(expected, 64); err == nil {
+		if f2, err := strconv.ParseFloat(current, 64); err == nil {
+			return f1 != f2
+		}
+	}
+	return expected != current
+}
+
+// normalizeDefaultValue strips the parentheses and quotes SQL Server adds to default definitions,
+// e.g. ((0)), (N'abc') and ('abc') are normalized to 0 and abc, and CURRENT_TIMESTAMP to getdate()
+func normalizeDefaultValue(value string) string {
+	value = strings.TrimSpace(value)
+	for len(value) >= 2 && value[0] == '(' && value[len(value)-1] == ')' && enclosedByParentheses(value) {
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+
+	if len(value) >= 3 && (value[0] == 'N' || value[0] == 'n') && value[1] == '\'' {
+		value = value[1:]
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+
+	value = strings.ToLower(value)
+	if value == "current_timestamp" {
+		return "getdate()"
+	}
+	return value
+}
+
+// enclosedByParentheses reports whether the first parenthesis of value is closed by its last character
+func enclosedByParentheses(value string) bool {
+	depth, quoted := 0, false
+	for idx, r := range value {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 && idx != len(value)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,211 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+type testColumnChange struct {
+	ID        uint
+	Name      string `gorm:"size:256"`
+	Text      string
+	Amount    float64
+	Ratio     float32 `gorm:"type:float(24)"`
+	Price     float64 `gorm:"precision:10;scale:2"`
+	CreatedAt time.Time
+	UpdatedAt time.Time `gorm:"precision:3;default:CURRENT_TIMESTAMP"`
+	Active    bool      `gorm:"default:true"`
+	Status    string    `gorm:"size:16;default:'new'"`
+	Count     int       `gorm:"not null;default:0"`
+	Code      string    `gorm:"size:32;collate:Latin1_General_CS_AS"`
+}
+
+func TestMigrator_ColumnChanged(t *testing.T) {
+	m := Migrator{migrator.Migrator{Config: migrator.Config{Dialector: Dialector{Config: &Config{}}}}}
+	s, err := schema.Parse(&testColumnChange{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+
+	column := func(dataType string, length, precision, scale int64, nullable bool, defaultValue string) migrator.ColumnType {
+		return migrator.ColumnType{
+			DataTypeValue:     sql.NullString{String: dataType, Valid: true},
+			LengthValue:       sql.NullInt64{Int64: length, Valid: length != 0},
+			DecimalSizeValue:  sql.NullInt64{Int64: precision, Valid: precision != 0},
+			ScaleValue:        sql.NullInt64{Int64: scale, Valid: precision != 0},
+			NullableValue:     sql.NullBool{Bool: nullable, Valid: true},
+			DefaultValueValue: sql.NullString{String: defaultValue, Valid: defaultValue != ""},
+		}
+	}
+
+	tests := []struct {
+		field      string
+		columnType gorm.ColumnType
+		want       bool
+	}{
+		{field: "ID", columnType: column("bigint", 0, 19, 0, false, ""), want: false},
+		{field: "ID", columnType: column("int", 0, 10, 0, false, ""), want: true},
+		{field: "Name", columnType: column("nvarchar", 256, 0, 0, true, ""), want: false},
+		{field: "Name", columnType: column("nvarchar", 128, 0, 0, true, ""), want: true},
+		{field: "Text", columnType: column("nvarchar", -1, 0, 0, true, ""), want: false},
+		{field: "Text", columnType: column("nvarchar", 4000, 0, 0, true, ""), want: true},
+		{field: "Text", columnType: column("varchar", -1, 0, 0, true, ""), want: true},
+		{field: "Amount", columnType: column("float", 0, 53, 0, true, ""), want: false},
+		{field: "Ratio", columnType: column("real", 0, 24, 0, true, ""), want: false},
+		{field: "Price", columnType: column("decimal", 0, 10, 2, true, ""), want: false},
+		{field: "Price", columnType: column("numeric", 0, 10, 2, true, ""), want: false},
+		{field: "Price", columnType: column("decimal", 0, 12, 2, true, ""), want: true},
+		{field: "CreatedAt", columnType: column("datetimeoffset", 0, 7, 0, true, ""), want: false},
+		{field: "CreatedAt", columnType: column("datetimeoffset", 0, 3, 0, true, ""), want: true},
+		{field: "UpdatedAt", columnType: column("datetimeoffset", 0, 3, 0, true, "getdate()"), want: false},
+		{field: "Active", columnType: column("bit", 0, 0, 0, true, "1"), want: false},
+		{field: "Active", columnType: column("bit", 0, 0, 0, true, "0"), want: true},
+		{field: "Status", columnType: column("nvarchar", 16, 0, 0, true, "new"), want: false},
+		{field: "Status", columnType: column("nvarchar", 16, 0, 0, true, "(N'new')"), want: false},
+		{field: "Status", columnType: column("nvarchar", 16, 0, 0, true, "old"), want: true},
+		{field: "Status", columnType: column("nvarchar", 16, 0, 0, true, ""), want: true},
+		{field: "Count", columnType: column("bigint", 0, 19, 0, false, "0"), want: false},
+		{field: "Count", columnType: column("bigint", 0, 19, 0, false, "((0))"), want: false},
+		{field: "Count", columnType: column("int", 0, 10, 0, false, "0"), want: true},
+		{field: "Code", columnType: &ColumnInfo{migratorColumnType: column("nvarchar", 32, 0, 0, true, ""), Collation: "latin1_general_cs_as"}, want: false},
+		{field: "Code", columnType: &ColumnInfo{migratorColumnType: column("nvarchar", 32, 0, 0, true, ""), Collation: "SQL_Latin1_General_CP1_CI_AS"}, want: true},
+		{field: "Name", columnType: &ColumnInfo{migratorColumnType: column("nvarchar", 256, 0, 0, true, ""), Collation: "SQL_Latin1_General_CP1_CI_AS"}, want: false},
+	}
+	for _, tt := range tests {
+		field := s.LookUpField(tt.field)
+		if got := m.dataTypeChanged(field, tt.columnType) || m.defaultValueChanged(field, tt.columnType) || collationChanged(field, tt.columnType); got != tt.want {
+			t.Errorf("changed(%s, %+v) = %v, want %v", tt.field, tt.columnType, got, tt.want)
+		}
+	}
+}
+
+type testColumnChangeKey struct {
+	Code string `gorm:"primaryKey;size:64;collate:Latin1_General_CS_AS"`
+}
+
+func TestMigrator_PrimaryKeyChanged(t *testing.T) {
+	m := Migrator{migrator.Migrator{Config: migrator.Config{Dialector: Dialector{Config: &Config{}}}}}
+	s, err := schema.Parse(&testColumnChangeKey{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+
+	column := func(length int64, collation string) gorm.ColumnType {
+		return &ColumnInfo{migratorColumnType: migrator.ColumnType{
+			DataTypeValue: sql.NullString{String: "nvarchar", Valid: true},
+			LengthValue:   sql.NullInt64{Int64: length, Valid: true},
+			NullableValue: sql.NullBool{Bool: false, Valid: true},
+		}, Collation: collation}
+	}
+
+	tests := []struct {
+		name       string
+		columnType gorm.ColumnType
+		want       bool
+	}{
+		{name: "unchanged", columnType: column(64, "Latin1_General_CS_AS"), want: false},
+		{name: "widened", columnType: column(32, "Latin1_General_CS_AS"), want: true},
+		{name: "collation", columnType: column(64, "SQL_Latin1_General_CP1_CI_AS"), want: true},
+	}
+	for _, tt := range tests {
+		field := s.LookUpField("Code")
+		if got := m.dataTypeChanged(field, tt.columnType) || collationChanged(field, tt.columnType); got != tt.want {
+			t.Errorf("changed of %s primary key = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+type testCollationTable struct {
+	ID   uint
+	Name string `gorm:"size:64"`
+	Code string `gorm:"size:32;collate:Latin1_General_CS_AS"`
+	Note string `gorm:"type:varchar(100)"`
+	Qty  int
+}
+
+func (testCollationTable) Collation() string { return "Latin1_General_CI_AI" }
+
+func TestDialector_DataTypeOfCollation(t *testing.T) {
+	dialector := Dialector{Config: &Config{}}
+	s, err := schema.Parse(&testCollationTable{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+
+	tests := map[string]string{
+		"Name": "nvarchar(64) COLLATE Latin1_General_CI_AI",
+		"Code": "nvarchar(32) COLLATE Latin1_General_CS_AS",
+		"Note": "varchar(100) COLLATE Latin1_General_CI_AI",
+		"Qty":  "bigint",
+	}
+	for name, want := range tests {
+		if got := dialector.DataTypeOf(s.LookUpField(name)); got != want {
+			t.Errorf("DataTypeOf(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+type testInvalidCollationTable struct {
+	ID   uint
+	Name string `gorm:"size:64;collate:Latin1_General_CI_AS) DROP TABLE users --"`
+}
+
+func TestDialector_DataTypeOfInvalidCollation(t *testing.T) {
+	dialector := Dialector{Config: &Config{}}
+	s, err := schema.Parse(&testInvalidCollationTable{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+
+	field := s.LookUpField("Name")
+	if _, err := collationOf(field); err == nil {
+		t.Errorf("expected invalid collation error")
+	}
+	if got := dialector.DataTypeOf(field); got != "nvarchar(64)" {
+		t.Errorf("DataTypeOf(Name) = %q, want %q", got, "nvarchar(64)")
+	}
+}
+
+type testInvalidSeedTable struct {
+	ID uint `gorm:"primaryKey;autoIncrementSeed:1e6"`
+}
+
+func TestIdentityOfInvalidSeed(t *testing.T) {
+	s, err := schema.Parse(&testInvalidSeedTable{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+	if _, _, err := identityOf(s.LookUpField("ID")); err == nil || !strings.Contains(err.Error(), "invalid autoIncrementSeed 1e6") {
+		t.Errorf("expected invalid seed error, got %v", err)
+	}
+}
+
+type testIdentityTable struct {
+	ID      uint  `gorm:"primaryKey;autoIncrementSeed:1000000"`
+	Counter int16 `gorm:"autoIncrement;autoIncrementSeed:-10;autoIncrementIncrement:5"`
+}
+
+func TestDialector_DataTypeOfIdentity(t *testing.T) {
+	dialector := Dialector{Config: &Config{}}
+	s, err := schema.Parse(&testIdentityTable{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+
+	tests := map[string]string{
+		"ID":      "bigint IDENTITY(1000000,1)",
+		"Counter": "int IDENTITY(-10,5)",
+	}
+	for name, want := range tests {
+		if got := dialector.DataTypeOf(s.LookUpField(name)); got != want {
+			t.Errorf("DataTypeOf(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package sqlserver
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// computedColumnOf returns the expression of a computed column declared by the computed tag, e.g.
+//
+//	Total float64 `gorm:"computed:(price * qty);persisted"`
+//
+// persisted reports whether the values are stored, which is required to index imprecise expressions
+func computedColumnOf(field *schema.Field) (expression string, persisted bool, ok bool) {
+	expression, ok = field.TagSettings["COMPUTED"]
+	if expression = strings.TrimSpace(expression); !ok || expression == "" {
+		return "", false, false
+	}
+	if expression[0] != '(' || !enclosedByParentheses(expression) {
+		expression = "(" + expression + ")"
+	}
+	return expression, isSwitchedOn(field.TagSettings, "PERSISTED"), true
+}
+
+// isComputedField reports whether field is a computed column, whose values are generated by the database
+func isComputedField(field *schema.Field) bool {
+	_, _, ok := computedColumnOf(field)
+	return ok
+}
+
+// computedColumnDefinition returns the column definition AS (expression) [PERSISTED [NOT NULL]] of a computed column,
+// computed columns have no data type and default value
+func computedColumnDefinition(field *schema.Field) string {
+	expression, persisted, _ := computedColumnOf(field)
+	definition := "AS " + expression
+	if persisted {
+		definition += " PERSISTED"
+		// only persisted computed columns can be declared as not null
+		if field.NotNull {
+			definition += " NOT NULL"
+		}
+	}
+	return definition
+}
+
+// withoutComputedColumns calls fc with the computed columns of the model omitted from INSERT and UPDATE statements,
+// SQL Server rejects values for them (error 271), the omitted columns of the statement are restored afterwards
+func withoutComputedColumns(db *gorm.DB, fc func()) {
+	omits := db.Statement.Omits
+	defer func() {
+		db.Statement.Omits = omits
+	}()
+
+	if db.Statement.Schema != nil {
+		for _, field := range db.Statement.Schema.Fields {
+			if field.DBName != "" && isComputedField(field) {
+				// the omits are copied, so the slice of the statement isn't appended to
+				db.Statement.Omits = append(db.Statement.Omits[:len(db.Statement.Omits):len(db.Statement.Omits)], field.DBName)
+			}
+		}
+	}
+	fc()
+}
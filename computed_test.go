@@ -0,0 +1,115 @@
+package sqlserver_test
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gorm.io/driver/sqlserver"
+)
+
+type testComputedItem struct {
+	ID    uint
+	Price float64
+	Qty   int
+	Total float64 `gorm:"computed:price * qty;persisted;index"`
+}
+
+func TestCreate_ComputedColumn(t *testing.T) {
+	db := openDryRunDB(t, sqlserver.Config{})
+
+	tx := db.Create(&testComputedItem{Price: 2.5, Qty: 4})
+	if tx.Error != nil {
+		t.Fatalf("failed to create, got error: %v", tx.Error)
+	}
+	want := `INSERT INTO "test_computed_items" ("price","qty") OUTPUT INSERTED."id", INSERTED."total" VALUES (@p1,@p2);`
+	if sql := tx.Statement.SQL.String(); sql != want {
+		t.Errorf("expected SQL %q, got %q", want, sql)
+	}
+	if len(tx.Statement.Omits) != 0 {
+		t.Errorf("expected the omitted columns of the statement to be kept, got %v", tx.Statement.Omits)
+	}
+
+	tx = db.Model(&testComputedItem{ID: 1}).Updates(map[string]interface{}{"qty": 5, "total": 10})
+	if tx.Error != nil {
+		t.Fatalf("failed to update, got error: %v", tx.Error)
+	}
+	want = `UPDATE "test_computed_items" SET "qty"=@p1 WHERE "id" = @p2`
+	if sql := tx.Statement.SQL.String(); sql != want {
+		t.Errorf("expected SQL %q, got %q", want, sql)
+	}
+}
+
+func TestMigrator_CreateComputedColumn(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{}).Session(&gorm.Session{Logger: recorder})
+
+	if err := db.Migrator().CreateTable(&testComputedItem{}); err != nil {
+		t.Fatalf("failed to create table, got error: %v", err)
+	}
+	want := `CREATE TABLE "dbo"."test_computed_items" ("id" bigint IDENTITY(1,1),"price" float,"qty" bigint,"total" AS (price * qty) PERSISTED,PRIMARY KEY ("id"))`
+	var found bool
+	for _, sql := range recorder.sqls {
+		found = found || sql == want
+	}
+	if !found {
+		t.Errorf("expected SQL %s, got %v", want, recorder.sqls)
+	}
+}
+
+type TestTableComputed struct {
+	ID    uint
+	Price float64
+	Qty   int
+	Total float64 `gorm:"computed:(price * qty);persisted;index"`
+}
+
+func (*TestTableComputed) TableName() string { return "test_table_computed" }
+
+func TestMigrator_ComputedColumn(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open(sqlserverDSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.Debug()
+	dm := db.Migrator()
+	defer func() {
+		if err = dm.DropTable(&TestTableComputed{}); err != nil {
+			t.Errorf("couldn't drop table test_table_computed, got error: %v", err)
+		}
+	}()
+
+	if err = dm.AutoMigrate(&TestTableComputed{}); err != nil {
+		t.Fatal(err)
+	}
+	// migrating again must not alter the computed column
+	if err = dm.AutoMigrate(&TestTableComputed{}); err != nil {
+		t.Fatal(err)
+	}
+
+	columnInfos, err := dm.(sqlserver.Migrator).ColumnInfos(&TestTableComputed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range columnInfos {
+		if computed := info.Name() == "total"; info.Computed != computed || info.Persisted != computed {
+			t.Errorf("unexpected computed column %+v", info)
+		}
+	}
+
+	item := TestTableComputed{Price: 2.5, Qty: 4}
+	if err = db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Total != 10 {
+		t.Errorf("expected computed total 10 to be read back, got %v", item.Total)
+	}
+	if err = db.Model(&item).Updates(TestTableComputed{Qty: 2, Total: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var result TestTableComputed
+	if err = db.First(&result, item.ID).Error; err != nil || result.Total != 5 {
+		t.Errorf("expected computed total 5 after update, got %v, error: %v", result.Total, err)
+	}
+}
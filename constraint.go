@@ -0,0 +1,153 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConstraintInfo is a foreign key or check constraint of a table with its state, the constraints of a table are
+// disabled by DisableConstraints, and they aren't trusted by the query optimizer after being enabled without checking
+// the existing rows, until they are validated by ValidateConstraints
+type ConstraintInfo struct {
+	Name string
+	// Table is the schema qualified name of the table
+	Table string
+	// Type is DependencyForeignKey or DependencyCheckConstraint
+	Type     string
+	Disabled bool
+	Trusted  bool
+	// NotForReplication constraints aren't enforced for replication agents, SQL Server never trusts them
+	NotForReplication bool
+}
+
+// constraintsOf returns the foreign key and check constraints of the table, or of all tables if table is empty,
+// untrusted excludes the NOT FOR REPLICATION constraints, as they can't be trusted
+func (m Migrator) constraintsOf(table string, untrusted bool) (constraints []ConstraintInfo, err error) {
+	var (
+		conditions []string
+		vars       []interface{}
+	)
+	if table != "" {
+		conditions = append(conditions, "parent_object_id = OBJECT_ID(?)")
+		vars = append(vars, table)
+	}
+	if untrusted {
+		conditions = append(conditions, "is_not_trusted = 1", "is_not_for_replication = 0")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var rows []struct {
+		Name                string
+		TableName           string
+		Type                string
+		IsDisabled          bool
+		IsNotTrusted        bool
+		IsNotForReplication bool
+	}
+	err = m.DB.Raw(
+		"SELECT name, OBJECT_SCHEMA_NAME(parent_object_id) + '.' + OBJECT_NAME(parent_object_id) AS table_name, type, is_disabled, is_not_trusted, is_not_for_replication FROM ("+
+			"SELECT name, parent_object_id, '"+DependencyForeignKey+"' AS type, is_disabled, is_not_trusted, is_not_for_replication FROM sys.foreign_keys WHERE is_ms_shipped = 0 "+
+			"UNION ALL SELECT name, parent_object_id, '"+DependencyCheckConstraint+"' AS type, is_disabled, is_not_trusted, is_not_for_replication FROM sys.check_constraints WHERE is_ms_shipped = 0"+
+			") c"+where+" ORDER BY table_name, name",
+		vars...,
+	).Scan(&rows).Error
+
+	for _, row := range rows {
+		constraints = append(constraints, ConstraintInfo{
+			Name:              row.Name,
+			Table:             row.TableName,
+			Type:              row.Type,
+			Disabled:          row.IsDisabled,
+			Trusted:           !row.IsNotTrusted,
+			NotForReplication: row.IsNotForReplication,
+		})
+	}
+	return
+}
+
+// GetConstraints returns the foreign key and check constraints of value's table with their state
+func (m Migrator) GetConstraints(value interface{}) (constraints []ConstraintInfo, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraints, err = m.constraintsOf(m.fullTableNameOf(stmt), false)
+		return err
+	})
+	return
+}
+
+// DisableConstraints disables the named foreign key and check constraints of value's table, or all of them if no
+// name is given, so rows violating them can be written, e.g. to load tables in any order
+func (m Migrator) DisableConstraints(value interface{}, names ...string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.checkConstraints(stmt, "NOCHECK CONSTRAINT", names)
+	})
+}
+
+// EnableConstraints enables the named foreign key and check constraints of value's table, or all of them if no
+// name is given, the existing rows are checked, so the constraints are trusted again
+func (m Migrator) EnableConstraints(value interface{}, names ...string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.checkConstraints(stmt, "WITH CHECK CHECK CONSTRAINT", names)
+	})
+}
+
+func (m Migrator) checkConstraints(stmt *gorm.Statement, action string, names []string) error {
+	table := clause.Table{Name: m.fullTableNameOf(stmt)}
+	if len(names) == 0 {
+		return m.DB.Exec("ALTER TABLE ? "+action+" ALL", table).Error
+	}
+	for _, name := range names {
+		if constraint, _ := m.GuessConstraintInterfaceAndTable(stmt, name); constraint != nil {
+			name = constraint.GetName()
+		}
+		if err := m.DB.Exec("ALTER TABLE ? "+action+" ?", table, clause.Column{Name: name}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UntrustedConstraints returns the disabled and not trusted foreign key and check constraints of the tables of values,
+// or of all tables of the database if no value is given. NOT FOR REPLICATION constraints are left out, they are never
+// trusted, even after being checked.
+func (m Migrator) UntrustedConstraints(values ...interface{}) ([]ConstraintInfo, error) {
+	if len(values) == 0 {
+		return m.constraintsOf("", true)
+	}
+
+	var constraints []ConstraintInfo
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			tableConstraints, err := m.constraintsOf(m.fullTableNameOf(stmt), true)
+			constraints = append(constraints, tableConstraints...)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return constraints, nil
+}
+
+// ValidateConstraints enables and checks the untrusted constraints of the tables of values, or of all tables of
+// the database if no value is given, so they are trusted again, it fails on the first constraint violated by existing rows.
+// NOT FOR REPLICATION constraints aren't validated, as they would stay untrusted anyway.
+func (m Migrator) ValidateConstraints(values ...interface{}) error {
+	constraints, err := m.UntrustedConstraints(values...)
+	if err != nil {
+		return err
+	}
+
+	for _, constraint := range constraints {
+		if err := m.DB.Exec(
+			"ALTER TABLE ? WITH CHECK CHECK CONSTRAINT ?", clause.Table{Name: constraint.Table}, clause.Column{Name: constraint.Name},
+		).Error; err != nil {
+			return fmt.Errorf("failed to validate constraint %s on %s: %w", constraint.Name, constraint.Table, err)
+		}
+	}
+	return nil
+}
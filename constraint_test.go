@@ -0,0 +1,131 @@
+package sqlserver_test
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gorm.io/driver/sqlserver"
+)
+
+func TestMigrator_DisableEnableConstraints(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{MigratorDefaultSchema: "dbo"}).Session(&gorm.Session{Logger: recorder})
+	dm := db.Migrator().(sqlserver.Migrator)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			name: "disable all",
+			run:  func() error { return dm.DisableConstraints(&testCascadeInvoice{}) },
+			want: `ALTER TABLE "dbo"."test_cascade_invoices" NOCHECK CONSTRAINT ALL`,
+		},
+		{
+			name: "disable named",
+			run:  func() error { return dm.DisableConstraints(&testCascadeInvoice{}, "Account") },
+			want: `ALTER TABLE "dbo"."test_cascade_invoices" NOCHECK CONSTRAINT "fk_test_cascade_invoices_account"`,
+		},
+		{
+			name: "enable all",
+			run:  func() error { return dm.EnableConstraints(&testCascadeInvoice{}) },
+			want: `ALTER TABLE "dbo"."test_cascade_invoices" WITH CHECK CHECK CONSTRAINT ALL`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("failed to run, got error: %v", err)
+			}
+			if len(recorder.sqls) == 0 || recorder.sqls[len(recorder.sqls)-1] != tt.want {
+				t.Errorf("expected SQL %s, got %v", tt.want, recorder.sqls)
+			}
+		})
+	}
+}
+
+type TestTableTrustedParent struct {
+	ID uint
+}
+
+func (*TestTableTrustedParent) TableName() string { return "test_table_trusted_parent" }
+
+type TestTableTrustedChild struct {
+	ID       uint
+	ParentID uint
+	Parent   TestTableTrustedParent
+	Qty      int `gorm:"check:chk_test_table_trusted_child_qty,qty >= 0"`
+}
+
+func (*TestTableTrustedChild) TableName() string { return "test_table_trusted_child" }
+
+func TestMigrator_UntrustedConstraints(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open(sqlserverDSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.Debug()
+	dm := db.Migrator().(sqlserver.Migrator)
+	defer func() {
+		if err = dm.DropTable(&TestTableTrustedChild{}, &TestTableTrustedParent{}); err != nil {
+			t.Errorf("couldn't drop tables, got error: %v", err)
+		}
+	}()
+
+	if err = dm.AutoMigrate(&TestTableTrustedChild{}, &TestTableTrustedParent{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.DisableConstraints(&TestTableTrustedChild{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the child is loaded before its parent
+	if err = db.Create(&TestTableTrustedChild{ParentID: 1, Qty: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Create(&TestTableTrustedParent{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	constraints, err := dm.GetConstraints(&TestTableTrustedChild{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(constraints) != 2 {
+		t.Fatalf("expected foreign key and check constraint, got %+v", constraints)
+	}
+	for _, constraint := range constraints {
+		if !constraint.Disabled || constraint.Trusted {
+			t.Errorf("expected disabled and untrusted constraint, got %+v", constraint)
+		}
+	}
+
+	if err = dm.ValidateConstraints(&TestTableTrustedChild{}); err != nil {
+		t.Fatal(err)
+	}
+	if constraints, err = dm.UntrustedConstraints(&TestTableTrustedChild{}); err != nil || len(constraints) != 0 {
+		t.Errorf("expected all constraints to be trusted, got %+v, error: %v", constraints, err)
+	}
+
+	// NOT FOR REPLICATION constraints are never trusted, so they aren't reported
+	if err = db.Exec("ALTER TABLE test_table_trusted_child ADD CONSTRAINT chk_test_table_trusted_child_replicated CHECK NOT FOR REPLICATION (qty < 1000)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.ValidateConstraints(&TestTableTrustedChild{}); err != nil {
+		t.Fatal(err)
+	}
+	if constraints, err = dm.UntrustedConstraints(&TestTableTrustedChild{}); err != nil || len(constraints) != 0 {
+		t.Errorf("expected NOT FOR REPLICATION constraint to be left out, got %+v, error: %v", constraints, err)
+	}
+	if constraints, err = dm.GetConstraints(&TestTableTrustedChild{}); err != nil || len(constraints) != 3 {
+		t.Fatalf("expected 3 constraints, got %+v, error: %v", constraints, err)
+	}
+	for _, constraint := range constraints {
+		if constraint.NotForReplication != (constraint.Name == "chk_test_table_trusted_child_replicated") {
+			t.Errorf("unexpected NotForReplication of %+v", constraint)
+		}
+	}
+}
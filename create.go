@@ -1,11 +1,16 @@
 package sqlserver
 
 import (
+	"database/sql"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 func Create(db *gorm.DB) {
@@ -19,103 +24,53 @@ func Create(db *gorm.DB) {
 		}
 	}
 
-	hasOutput := false
+	var (
+		hasOutput bool
+		batches   []identityBatch
+	)
 	if db.Statement.SQL.String() == "" {
+		var values clause.Values
+		withoutComputedColumns(db, func() {
+			values = callbacks.ConvertToCreateValues(db.Statement)
+		})
 		var (
-			values                  = callbacks.ConvertToCreateValues(db.Statement)
 			c                       = db.Statement.Clauses["ON CONFLICT"]
 			onConflict, hasConflict = c.Expression.(clause.OnConflict)
 		)
 
 		if hasConflict {
-			if len(db.Statement.Schema.PrimaryFields) > 0 {
-				columnsMap := map[string]bool{}
-				for _, column := range values.Columns {
-					columnsMap[column.Name] = true
-				}
-
-				for _, field := range db.Statement.Schema.PrimaryFields {
-					if _, ok := columnsMap[field.DBName]; !ok {
-						hasConflict = false
-					}
-				}
-			} else {
-				hasConflict = false
+			if onConflict, hasConflict = prepareOnConflict(db, onConflict, values); db.Error != nil {
+				return
 			}
 		}
 
-		if hasConflict {
-			hasOutput = MergeCreate(db, onConflict, values)
-		} else {
-			setIdentityInsert := false
-
-			if db.Statement.Schema != nil {
-				if field := db.Statement.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
-					switch db.Statement.ReflectValue.Kind() {
-					case reflect.Struct:
-						_, isZero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue)
-						setIdentityInsert = !isZero
-					case reflect.Slice, reflect.Array:
-						for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
-							obj := db.Statement.ReflectValue.Index(i)
-							if reflect.Indirect(obj).Kind() == reflect.Struct {
-								_, isZero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue.Index(i))
-								setIdentityInsert = !isZero
-							}
-							break
-						}
-					}
-
-					if setIdentityInsert {
-						db.Statement.WriteString("SET IDENTITY_INSERT ")
-						db.Statement.WriteQuoted(db.Statement.Table)
-						db.Statement.WriteString(" ON;")
-					}
-				}
+		triggerSafe := triggerSafeOutput(db)
+		for idx, batch := range splitIdentityBatches(db, values) {
+			if triggerSafe {
+				db.Statement.Settings.Store(outputTableKey, "@output"+strconv.Itoa(idx))
 			}
 
-			db.Statement.AddClauseIfNotExists(clause.Insert{})
-			db.Statement.Build("INSERT")
-			db.Statement.WriteByte(' ')
-
-			db.Statement.AddClause(values)
-			if values, ok := db.Statement.Clauses["VALUES"].Expression.(clause.Values); ok {
-				if len(values.Columns) > 0 {
-					db.Statement.WriteByte('(')
-					for idx, column := range values.Columns {
-						if idx > 0 {
-							db.Statement.WriteByte(',')
-						}
-						db.Statement.WriteQuoted(column)
-					}
-					db.Statement.WriteByte(')')
-
-					hasOutput = outputInserted(db)
-
-					db.Statement.WriteString(" VALUES ")
-
-					for idx, value := range values.Values {
-						if idx > 0 {
-							db.Statement.WriteByte(',')
-						}
-
-						db.Statement.WriteByte('(')
-						db.Statement.AddVar(db.Statement, value...)
-						db.Statement.WriteByte(')')
-					}
+			if batch.identityInsert {
+				db.Statement.WriteString("SET IDENTITY_INSERT ")
+				db.Statement.WriteQuoted(db.Statement.Table)
+				db.Statement.WriteString(" ON;")
+			}
 
-					db.Statement.WriteString(";")
-				} else {
-					db.Statement.WriteString("DEFAULT VALUES;")
-				}
+			if hasConflict && hasConflictColumns(onConflict, batch.values) {
+				batch.hasOutput = MergeCreate(db, onConflict, batch.values)
+			} else {
+				batch.hasOutput = insertValues(db, batch.values)
 			}
+			hasOutput = hasOutput || batch.hasOutput
 
-			if setIdentityInsert {
+			if batch.identityInsert {
 				db.Statement.WriteString("SET IDENTITY_INSERT ")
 				db.Statement.WriteQuoted(db.Statement.Table)
 				db.Statement.WriteString(" OFF;")
 			}
+			batches = append(batches, batch)
 		}
+		db.Statement.Settings.Delete(outputTableKey)
 	}
 
 	if !db.DryRun && db.Error == nil {
@@ -123,7 +78,7 @@ func Create(db *gorm.DB) {
 			rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
 			if db.AddError(err) == nil {
 				defer rows.Close()
-				gorm.Scan(rows, db, gorm.ScanUpdate|gorm.ScanOnConflictDoNothing)
+				scanBatches(rows, db, batches)
 				if db.Statement.Result != nil {
 					db.Statement.Result.RowsAffected = db.RowsAffected
 				}
@@ -141,9 +96,325 @@ func Create(db *gorm.DB) {
 	}
 }
 
+// MergeOption customizes the MERGE statement built for upserts, e.g.
+//
+//	db.Clauses(clause.OnConflict{UpdateAll: true}, sqlserver.MergeOption{HoldLock: true, ActionColumn: "merge_action"}).Create(&events)
+//
+// HoldLock adds WITH (HOLDLOCK) to the target table, which makes the upsert safe
+// against concurrent inserts of the same key. ActionColumn outputs $action (INSERT
+// or UPDATE) under the given column name so it can be scanned back into a read-only
+// field of the model, e.g. `gorm:"->;-:migration;column:merge_action"`.
+type MergeOption struct {
+	HoldLock     bool
+	ActionColumn string
+}
+
+// Name merge option clause name
+func (MergeOption) Name() string {
+	return "MERGE OPTION"
+}
+
+// Build merge option is consumed by MergeCreate, nothing to build
+func (MergeOption) Build(clause.Builder) {
+}
+
+// MergeClause merge option clauses
+func (opt MergeOption) MergeClause(c *clause.Clause) {
+	c.Expression = opt
+}
+
+func mergeOptionOf(db *gorm.DB) MergeOption {
+	opt, _ := db.Statement.Clauses["MERGE OPTION"].Expression.(MergeOption)
+	if config := configOf(db); config.MergeHoldLock {
+		opt.HoldLock = true
+	}
+	return opt
+}
+
+// identityBatch is a part of the rows to create, the identity values of its rows are either all generated by the database or all provided by the caller
+type identityBatch struct {
+	values         clause.Values
+	rows           []int
+	identityInsert bool
+	hasOutput      bool
+}
+
+// splitIdentityBatches splits values into rows with generated identity values and rows with caller-supplied identity values,
+// as the latter have to be inserted with IDENTITY_INSERT ON, which requires an explicit value for every row
+func splitIdentityBatches(db *gorm.DB, values clause.Values) []identityBatch {
+	idx := identityColumnIndex(db, values)
+	if idx < 0 {
+		rows := make([]int, len(values.Values))
+		for i := range rows {
+			rows[i] = i
+		}
+		return []identityBatch{{values: values, rows: rows}}
+	}
+
+	var (
+		generated = identityBatch{values: clause.Values{Columns: make([]clause.Column, 0, len(values.Columns)-1)}}
+		provided  = identityBatch{values: clause.Values{Columns: values.Columns}, identityInsert: true}
+		batches   = make([]identityBatch, 0, 2)
+	)
+	generated.values.Columns = append(generated.values.Columns, values.Columns[:idx]...)
+	generated.values.Columns = append(generated.values.Columns, values.Columns[idx+1:]...)
+
+	for i, value := range values.Values {
+		if isGeneratedIdentity(value[idx]) {
+			row := make([]interface{}, 0, len(value)-1)
+			row = append(row, value[:idx]...)
+			generated.values.Values = append(generated.values.Values, append(row, value[idx+1:]...))
+			generated.rows = append(generated.rows, i)
+		} else {
+			provided.values.Values = append(provided.values.Values, value)
+			provided.rows = append(provided.rows, i)
+		}
+	}
+
+	if len(generated.rows) > 0 {
+		batches = append(batches, generated)
+	}
+	if len(provided.rows) > 0 {
+		batches = append(batches, provided)
+	}
+	return batches
+}
+
+// identityColumnIndex returns the index of the auto-increment primary key in values' columns, or -1 if it is not there
+func identityColumnIndex(db *gorm.DB, values clause.Values) int {
+	if db.Statement.Schema != nil {
+		if field := db.Statement.Schema.PrioritizedPrimaryField; field != nil && field.AutoIncrement {
+			for idx, column := range values.Columns {
+				if column.Name == field.DBName {
+					return idx
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func isIdentityColumn(db *gorm.DB, name string) bool {
+	if db.Statement.Schema == nil {
+		return false
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	return field != nil && field.AutoIncrement && field.DBName == name
+}
+
+// isGeneratedIdentity reports whether the identity value is left to the database, zero values are replaced with DEFAULT expressions by ConvertToCreateValues
+func isGeneratedIdentity(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	_, ok := value.(clause.Expression)
+	return ok
+}
+
+// identityInsertOf reports whether values carry caller-supplied identity values for all rows
+func identityInsertOf(db *gorm.DB, values clause.Values) bool {
+	idx := identityColumnIndex(db, values)
+	if idx < 0 {
+		return false
+	}
+	for _, value := range values.Values {
+		if isGeneratedIdentity(value[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareOnConflict resolves the columns to match rows on, which are OnConflict.Columns or the primary keys of the model,
+// and expands UpdateAll for creates without a model, which is left to ConvertToCreateValues otherwise
+func prepareOnConflict(db *gorm.DB, onConflict clause.OnConflict, values clause.Values) (clause.OnConflict, bool) {
+	if len(onConflict.Columns) == 0 {
+		if db.Statement.Schema == nil || len(db.Statement.Schema.PrimaryFields) == 0 {
+			db.AddError(fmt.Errorf("failed to upsert into %s, OnConflict.Columns or primary keys are required: %w", db.Statement.Table, gorm.ErrPrimaryKeyRequired))
+			return onConflict, false
+		}
+		for _, field := range db.Statement.Schema.PrimaryFields {
+			onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+		}
+	} else if db.Statement.Schema != nil {
+		columns := make([]clause.Column, 0, len(onConflict.Columns))
+		for _, column := range onConflict.Columns {
+			if field := db.Statement.Schema.LookUpField(column.Name); field != nil {
+				column.Name = field.DBName
+			}
+			columns = append(columns, column)
+		}
+		onConflict.Columns = columns
+	}
+
+	if db.Statement.Schema == nil && onConflict.UpdateAll && len(onConflict.DoUpdates) == 0 {
+		conflictColumns := map[string]bool{}
+		for _, column := range onConflict.Columns {
+			conflictColumns[column.Name] = true
+		}
+
+		columns := make([]string, 0, len(values.Columns))
+		for _, column := range values.Columns {
+			if !conflictColumns[column.Name] {
+				columns = append(columns, column.Name)
+			}
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	}
+	return onConflict, true
+}
+
+// hasConflictColumns reports whether all columns to match rows on have values, otherwise rows can't be matched and are just inserted
+func hasConflictColumns(onConflict clause.OnConflict, values clause.Values) bool {
+	columnsMap := map[string]bool{}
+	for _, column := range values.Columns {
+		columnsMap[column.Name] = true
+	}
+
+	for _, column := range onConflict.Columns {
+		if !columnsMap[column.Name] {
+			return false
+		}
+	}
+	return len(onConflict.Columns) > 0
+}
+
+// scanBatches scans the OUTPUT result set of every batch back into the rows of the batch
+func scanBatches(rows *sql.Rows, db *gorm.DB, batches []identityBatch) {
+	if len(batches) == 1 {
+		gorm.Scan(rows, db, gorm.ScanUpdate|gorm.ScanOnConflictDoNothing)
+		return
+	}
+
+	reflectValue := db.Statement.ReflectValue
+	defer func() {
+		db.Statement.ReflectValue = reflectValue
+	}()
+
+	elemType := reflectValue.Type().Elem()
+	if elemType.Kind() != reflect.Ptr {
+		elemType = reflect.PtrTo(elemType)
+	}
+
+	var (
+		rowsAffected int64
+		scanned      bool
+	)
+	for _, batch := range batches {
+		if !batch.hasOutput {
+			continue
+		}
+		if scanned && !rows.NextResultSet() {
+			break
+		}
+		scanned = true
+
+		elems := reflect.MakeSlice(reflect.SliceOf(elemType), 0, len(batch.rows))
+		for _, row := range batch.rows {
+			elem := reflectValue.Index(row)
+			if elem.Kind() != reflect.Ptr {
+				elem = elem.Addr()
+			}
+			elems = reflect.Append(elems, elem)
+		}
+
+		db.Statement.ReflectValue = elems
+		gorm.Scan(rows, db, gorm.ScanUpdate|gorm.ScanOnConflictDoNothing)
+		rowsAffected += db.RowsAffected
+	}
+	db.RowsAffected = rowsAffected
+}
+
+func insertValues(db *gorm.DB, values clause.Values) (hasOutput bool) {
+	var (
+		outputTable   = outputTableOf(db)
+		identityField *schema.Field
+	)
+	if outputTable != "" {
+		if identityField = scopeIdentityField(db, values); identityField != nil {
+			outputTable = ""
+		} else if len(outputFields(db)) > 0 {
+			declareOutputTable(db, outputTable, "")
+		} else {
+			outputTable = ""
+		}
+	}
+
+	db.Statement.AddClauseIfNotExists(clause.Insert{})
+	db.Statement.Build("INSERT")
+	db.Statement.WriteByte(' ')
+
+	db.Statement.AddClause(values)
+	if values, ok := db.Statement.Clauses["VALUES"].Expression.(clause.Values); ok {
+		if len(values.Columns) > 0 {
+			db.Statement.WriteByte('(')
+			for idx, column := range values.Columns {
+				if idx > 0 {
+					db.Statement.WriteByte(',')
+				}
+				db.Statement.WriteQuoted(column)
+			}
+			db.Statement.WriteByte(')')
+
+			if identityField == nil {
+				hasOutput = outputInserted(db, outputTable)
+				if hasOutput && outputTable != "" {
+					db.Statement.WriteString(" INTO ")
+					db.Statement.WriteString(outputTable)
+				}
+			}
+
+			db.Statement.WriteString(" VALUES ")
+
+			for idx, value := range values.Values {
+				if idx > 0 {
+					db.Statement.WriteByte(',')
+				}
+
+				db.Statement.WriteByte('(')
+				db.Statement.AddVar(db.Statement, value...)
+				db.Statement.WriteByte(')')
+			}
+
+			db.Statement.WriteString(";")
+		} else {
+			db.Statement.WriteString("DEFAULT VALUES;")
+		}
+	}
+
+	if identityField != nil {
+		db.Statement.WriteString("SELECT CAST(SCOPE_IDENTITY() AS ")
+		db.Statement.WriteString(outputDataTypeOf(db, identityField))
+		db.Statement.WriteString(") AS ")
+		db.Statement.WriteQuoted(identityField.DBName)
+		db.Statement.WriteByte(';')
+		hasOutput = true
+	} else if outputTable != "" {
+		selectOutputTable(db, outputTable)
+	}
+	return hasOutput
+}
+
 func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values) bool {
+	var (
+		opt            = mergeOptionOf(db)
+		identityInsert = identityInsertOf(db, values)
+		outputTable    = outputTableOf(db)
+	)
+
+	if outputTable != "" {
+		if len(outputFields(db)) > 0 || opt.ActionColumn != "" {
+			declareOutputTable(db, outputTable, opt.ActionColumn)
+		} else {
+			outputTable = ""
+		}
+	}
+
 	db.Statement.WriteString("MERGE INTO ")
 	db.Statement.WriteQuoted(db.Statement.Table)
+	if opt.HoldLock {
+		db.Statement.WriteString(" WITH (HOLDLOCK)")
+	}
 	db.Statement.WriteString(" USING (VALUES")
 	for idx, value := range values.Values {
 		if idx > 0 {
@@ -165,11 +436,20 @@ func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values
 	db.Statement.WriteString(") ON ")
 
 	var where clause.Where
-	for _, field := range db.Statement.Schema.PrimaryFields {
-		where.Exprs = append(where.Exprs, clause.Eq{
-			Column: clause.Column{Table: db.Statement.Table, Name: field.DBName},
-			Value:  clause.Column{Table: "excluded", Name: field.DBName},
-		})
+	if len(onConflict.Columns) > 0 {
+		for _, column := range onConflict.Columns {
+			where.Exprs = append(where.Exprs, clause.Eq{
+				Column: clause.Column{Table: db.Statement.Table, Name: column.Name},
+				Value:  clause.Column{Table: "excluded", Name: column.Name},
+			})
+		}
+	} else if db.Statement.Schema != nil {
+		for _, field := range db.Statement.Schema.PrimaryFields {
+			where.Exprs = append(where.Exprs, clause.Eq{
+				Column: clause.Column{Table: db.Statement.Table, Name: field.DBName},
+				Value:  clause.Column{Table: "excluded", Name: field.DBName},
+			})
+		}
 	}
 	where.Build(db.Statement)
 
@@ -182,7 +462,7 @@ func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values
 
 	written := false
 	for _, column := range values.Columns {
-		if db.Statement.Schema.PrioritizedPrimaryField == nil || !db.Statement.Schema.PrioritizedPrimaryField.AutoIncrement || db.Statement.Schema.PrioritizedPrimaryField.DBName != column.Name {
+		if identityInsert || !isIdentityColumn(db, column.Name) {
 			if written {
 				db.Statement.WriteByte(',')
 			}
@@ -195,7 +475,7 @@ func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values
 
 	written = false
 	for _, column := range values.Columns {
-		if db.Statement.Schema.PrioritizedPrimaryField == nil || !db.Statement.Schema.PrioritizedPrimaryField.AutoIncrement || db.Statement.Schema.PrioritizedPrimaryField.DBName != column.Name {
+		if identityInsert || !isIdentityColumn(db, column.Name) {
 			if written {
 				db.Statement.WriteByte(',')
 			}
@@ -208,27 +488,207 @@ func MergeCreate(db *gorm.DB, onConflict clause.OnConflict, values clause.Values
 	}
 
 	db.Statement.WriteString(")")
-	hasOutput := outputInserted(db)
+	hasOutput := outputInserted(db, outputTable)
+	if opt.ActionColumn != "" {
+		if hasOutput {
+			db.Statement.WriteString(", $action AS ")
+		} else {
+			db.Statement.WriteString(" OUTPUT $action AS ")
+			hasOutput = true
+		}
+		db.Statement.WriteQuoted(opt.ActionColumn)
+	}
+	if outputTable != "" {
+		db.Statement.WriteString(" INTO ")
+		db.Statement.WriteString(outputTable)
+	}
 	db.Statement.WriteString(";")
+	if outputTable != "" {
+		selectOutputTable(db, outputTable)
+	}
 	return hasOutput
 }
 
-func outputInserted(db *gorm.DB) (hasOutput bool) {
-	if db.Statement.Schema != nil && len(db.Statement.Schema.FieldsWithDefaultDBValue) > 0 {
-		for _, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
-			if hasOutput {
-				db.Statement.WriteString(",")
+func outputInserted(db *gorm.DB, outputTable string) (hasOutput bool) {
+	if returning, ok := returningOf(db); ok && len(returning.Columns) == 0 && outputTable == "" && db.Statement.Schema != nil {
+		db.Statement.WriteString(" OUTPUT INSERTED.*")
+		return true
+	}
+
+	for _, field := range outputFields(db) {
+		if !hasOutput {
+			db.Statement.WriteString(" OUTPUT INSERTED.")
+			hasOutput = true
+		} else {
+			db.Statement.WriteString(", INSERTED.")
+		}
+		db.Statement.AddVar(db.Statement, clause.Column{Name: field.DBName})
+	}
+	return hasOutput
+}
+
+// outputFields returns the fields read back after creating, which are the columns of clause.Returning if given,
+// all table columns for an empty clause.Returning, and the fields whose values are generated by the database
+// including computed columns otherwise
+func outputFields(db *gorm.DB) (fields []*schema.Field) {
+	if db.Statement.Schema == nil {
+		return nil
+	}
+
+	if returning, ok := returningOf(db); ok {
+		if len(returning.Columns) == 0 {
+			for _, dbName := range db.Statement.Schema.DBNames {
+				if field := db.Statement.Schema.FieldsByDBName[dbName]; field.Readable && !field.IgnoreMigration {
+					fields = append(fields, field)
+				}
 			}
-			if field.Readable {
-				if !hasOutput {
-					db.Statement.WriteString(" OUTPUT INSERTED.")
-					hasOutput = true
-				} else {
-					db.Statement.WriteString(" INSERTED.")
+		} else {
+			for _, column := range returning.Columns {
+				if field := db.Statement.Schema.LookUpField(column.Name); field != nil && field.Readable {
+					fields = append(fields, field)
 				}
-				db.Statement.AddVar(db.Statement, clause.Column{Name: field.DBName})
 			}
 		}
+		return fields
 	}
-	return hasOutput
+
+	for _, field := range db.Statement.Schema.FieldsWithDefaultDBValue {
+		if field.Readable {
+			fields = append(fields, field)
+		}
+	}
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName != "" && field.Readable && !field.HasDefaultValue && isComputedField(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func returningOf(db *gorm.DB) (returning clause.Returning, ok bool) {
+	if c, exists := db.Statement.Clauses["RETURNING"]; exists {
+		returning, ok = c.Expression.(clause.Returning)
+	}
+	return
+}
+
+const outputTableKey = "sqlserver:output_table"
+
+// TableWithTriggers can be implemented by models whose table has enabled triggers. SQL Server rejects a plain
+// OUTPUT clause on such tables (error 334), so the generated values are returned through a table variable instead.
+type TableWithTriggers interface {
+	HasTriggers() bool
+}
+
+// triggerSafeOutput reports whether the generated values of the current statement have to be returned without a plain OUTPUT clause
+func triggerSafeOutput(db *gorm.DB) bool {
+	config := configOf(db)
+	if config.OutputIntoTableVariable {
+		return true
+	}
+
+	if db.Statement.Schema != nil {
+		if tabler, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(TableWithTriggers); ok {
+			return tabler.HasTriggers()
+		}
+	}
+
+	if config.DetectTriggers && !db.DryRun && config.triggerTables != nil {
+		if hasTriggers, ok := config.triggerTables.Load(db.Statement.Table); ok {
+			return hasTriggers.(bool)
+		}
+
+		var count int
+		if err := db.Statement.ConnPool.QueryRowContext(
+			db.Statement.Context, "SELECT count(*) FROM sys.triggers WHERE parent_id = OBJECT_ID(@p1) AND is_disabled = 0", db.Statement.Table,
+		).Scan(&count); err != nil {
+			db.AddError(fmt.Errorf("failed to detect triggers of %s: %w", db.Statement.Table, err))
+			return false
+		}
+		config.triggerTables.Store(db.Statement.Table, count > 0)
+		return count > 0
+	}
+	return false
+}
+
+// ResetTriggerCache forgets the detected triggers of the tables, or of all tables if no table is given, so they are
+// looked up again on the next create, e.g. after creating or dropping triggers at runtime
+func (dialector Dialector) ResetTriggerCache(tables ...string) {
+	if dialector.Config == nil || dialector.Config.triggerTables == nil {
+		return
+	}
+	if len(tables) == 0 {
+		dialector.Config.triggerTables.Range(func(key, _ interface{}) bool {
+			dialector.Config.triggerTables.Delete(key)
+			return true
+		})
+	}
+	for _, table := range tables {
+		dialector.Config.triggerTables.Delete(table)
+	}
+}
+
+// outputTableOf returns the table variable the generated values are output into, an empty string means a plain OUTPUT clause
+func outputTableOf(db *gorm.DB) string {
+	if v, ok := db.Statement.Settings.Load(outputTableKey); ok {
+		return v.(string)
+	}
+	return ""
+}
+
+// scopeIdentityField returns the identity field if it is the only value to read back from a single row insert,
+// which then can be selected with SCOPE_IDENTITY() instead of a table variable
+func scopeIdentityField(db *gorm.DB, values clause.Values) *schema.Field {
+	if len(values.Values) != 1 {
+		return nil
+	}
+	if fields := outputFields(db); len(fields) == 1 {
+		if field := db.Statement.Schema.PrioritizedPrimaryField; field == fields[0] && field.AutoIncrement {
+			return field
+		}
+	}
+	return nil
+}
+
+func declareOutputTable(db *gorm.DB, table string, actionColumn string) {
+	db.Statement.WriteString("DECLARE ")
+	db.Statement.WriteString(table)
+	db.Statement.WriteString(" TABLE (")
+	for idx, field := range outputFields(db) {
+		if idx > 0 {
+			db.Statement.WriteByte(',')
+		}
+		db.Statement.WriteQuoted(field.DBName)
+		db.Statement.WriteByte(' ')
+		db.Statement.WriteString(outputDataTypeOf(db, field))
+	}
+	if actionColumn != "" {
+		if len(outputFields(db)) > 0 {
+			db.Statement.WriteByte(',')
+		}
+		db.Statement.WriteQuoted(actionColumn)
+		db.Statement.WriteString(" nvarchar(10)")
+	}
+	db.Statement.WriteString(");")
+}
+
+func selectOutputTable(db *gorm.DB, table string) {
+	db.Statement.WriteString("SELECT * FROM ")
+	db.Statement.WriteString(table)
+	db.Statement.WriteByte(';')
+}
+
+// outputDataTypeOf returns the data type of field without its IDENTITY property
+func outputDataTypeOf(db *gorm.DB, field *schema.Field) string {
+	var dataType string
+	if m, ok := db.Migrator().(interface{ DataTypeOf(*schema.Field) string }); ok {
+		dataType = m.DataTypeOf(field)
+	} else {
+		dataType = db.Dialector.DataTypeOf(field)
+	}
+
+	if idx := strings.Index(strings.ToUpper(dataType), " IDENTITY"); idx >= 0 {
+		dataType = dataType[:idx]
+	}
+	return dataType
 }
@@ -0,0 +1,315 @@
+package sqlserver_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type testUpsertUser struct {
+	ID     uint `gorm:"primaryKey"`
+	Name   string
+	Action string `gorm:"->;-:migration;column:merge_action"`
+}
+
+func openDryRunDB(t *testing.T, config sqlserver.Config) *gorm.DB {
+	t.Helper()
+	config.DSN = sqlserverDSN
+	// the default schema of the database user can't be queried in dry run mode
+	if config.MigratorDefaultSchema == "" {
+		config.MigratorDefaultSchema = "dbo"
+	}
+	db, err := gorm.Open(sqlserver.New(config), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to open dry run db, got error: %v", err)
+	}
+	return db
+}
+
+func TestMergeCreate_MergeOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  sqlserver.Config
+		clauses []clause.Expression
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "plain merge",
+			clauses: []clause.Expression{clause.OnConflict{UpdateAll: true}},
+			want:    []string{`MERGE INTO "test_upsert_users" USING`},
+			notWant: []string{"HOLDLOCK", "$action"},
+		},
+		{
+			name:    "config hold lock",
+			config:  sqlserver.Config{MergeHoldLock: true},
+			clauses: []clause.Expression{clause.OnConflict{UpdateAll: true}},
+			want:    []string{`MERGE INTO "test_upsert_users" WITH (HOLDLOCK) USING`},
+		},
+		{
+			name:    "clause hold lock and action",
+			clauses: []clause.Expression{clause.OnConflict{UpdateAll: true}, sqlserver.MergeOption{HoldLock: true, ActionColumn: "merge_action"}},
+			want:    []string{"WITH (HOLDLOCK)", `OUTPUT INSERTED."id", $action AS "merge_action";`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openDryRunDB(t, tt.config)
+			user := testUpsertUser{ID: 1, Name: "jinzhu"}
+			sql := db.Clauses(tt.clauses...).Create(&user).Statement.SQL.String()
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("expected SQL to contain %q, got %q", want, sql)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(sql, notWant) {
+					t.Errorf("expected SQL not to contain %q, got %q", notWant, sql)
+				}
+			}
+		})
+	}
+}
+
+func TestCreate_IdentityInsert(t *testing.T) {
+	db := openDryRunDB(t, sqlserver.Config{})
+
+	tests := []struct {
+		name string
+		tx   func(tx *gorm.DB) *gorm.DB
+		want string
+	}{
+		{
+			name: "generated identities",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&[]testUpsertUser{{Name: "a"}, {Name: "b"}})
+			},
+			want: `INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED."id" VALUES (@p1),(@p2);`,
+		},
+		{
+			name: "provided identities",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&[]testUpsertUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}})
+			},
+			want: `SET IDENTITY_INSERT "test_upsert_users" ON;INSERT INTO "test_upsert_users" ("name","id") OUTPUT INSERTED."id" VALUES (@p1,@p2),(@p3,@p4);SET IDENTITY_INSERT "test_upsert_users" OFF;`,
+		},
+		{
+			name: "mixed identities",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&[]testUpsertUser{{Name: "a"}, {ID: 2, Name: "b"}, {Name: "c"}})
+			},
+			want: `INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED."id" VALUES (@p1),(@p2);` +
+				`SET IDENTITY_INSERT "test_upsert_users" ON;INSERT INTO "test_upsert_users" ("name","id") OUTPUT INSERTED."id" VALUES (@p3,@p4);SET IDENTITY_INSERT "test_upsert_users" OFF;`,
+		},
+		{
+			name: "merge provided identities",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&[]testUpsertUser{{ID: 1, Name: "a"}})
+			},
+			want: `SET IDENTITY_INSERT "test_upsert_users" ON;MERGE INTO "test_upsert_users" USING (VALUES(@p1,@p2)) AS excluded ("name","id") ON "test_upsert_users"."id" = "excluded"."id" WHEN MATCHED THEN UPDATE SET "name"="excluded"."name" WHEN NOT MATCHED THEN INSERT ("name","id") VALUES ("excluded"."name","excluded"."id") OUTPUT INSERTED."id";SET IDENTITY_INSERT "test_upsert_users" OFF;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := tt.tx(db.Session(&gorm.Session{}))
+			if tx.Error != nil {
+				t.Fatalf("failed to create, got error: %v", tx.Error)
+			}
+			if sql := tx.Statement.SQL.String(); sql != tt.want {
+				t.Errorf("expected SQL %q, got %q", tt.want, sql)
+			}
+		})
+	}
+}
+
+type testTriggerUser struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (testTriggerUser) HasTriggers() bool { return true }
+
+func TestCreate_TriggerSafeOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		config sqlserver.Config
+		tx     func(tx *gorm.DB) *gorm.DB
+		want   string
+	}{
+		{
+			name:   "config scope identity",
+			config: sqlserver.Config{OutputIntoTableVariable: true},
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&testUpsertUser{Name: "a"})
+			},
+			want: `INSERT INTO "test_upsert_users" ("name") VALUES (@p1);SELECT CAST(SCOPE_IDENTITY() AS bigint) AS "id";`,
+		},
+		{
+			name:   "config table variable",
+			config: sqlserver.Config{OutputIntoTableVariable: true},
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&[]testUpsertUser{{Name: "a"}, {Name: "b"}})
+			},
+			want: `DECLARE @output0 TABLE ("id" bigint);INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED."id" INTO @output0 VALUES (@p1),(@p2);SELECT * FROM @output0;`,
+		},
+		{
+			name: "model with triggers",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Create(&testTriggerUser{Name: "a"})
+			},
+			want: `DECLARE @output0 TABLE ("created_at" datetimeoffset,"id" bigint);INSERT INTO "test_trigger_users" ("name") OUTPUT INSERTED."created_at", INSERTED."id" INTO @output0 VALUES (@p1);SELECT * FROM @output0;`,
+		},
+		{
+			name:   "merge",
+			config: sqlserver.Config{OutputIntoTableVariable: true},
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.OnConflict{DoNothing: true}, sqlserver.MergeOption{ActionColumn: "merge_action"}).Create(&testUpsertUser{ID: 1, Name: "a"})
+			},
+			want: `SET IDENTITY_INSERT "test_upsert_users" ON;DECLARE @output0 TABLE ("id" bigint,"merge_action" nvarchar(10));MERGE INTO "test_upsert_users" USING (VALUES(@p1,@p2)) AS excluded ("name","id") ON "test_upsert_users"."id" = "excluded"."id" WHEN NOT MATCHED THEN INSERT ("name","id") VALUES ("excluded"."name","excluded"."id") OUTPUT INSERTED."id", $action AS "merge_action" INTO @output0;SELECT * FROM @output0;SET IDENTITY_INSERT "test_upsert_users" OFF;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := tt.tx(openDryRunDB(t, tt.config))
+			if tx.Error != nil {
+				t.Fatalf("failed to create, got error: %v", tx.Error)
+			}
+			if sql := tx.Statement.SQL.String(); sql != tt.want {
+				t.Errorf("expected SQL %q, got %q", tt.want, sql)
+			}
+		})
+	}
+}
+
+func TestCreate_Returning(t *testing.T) {
+	tests := []struct {
+		name   string
+		config sqlserver.Config
+		tx     func(tx *gorm.DB) *gorm.DB
+		want   string
+	}{
+		{
+			name: "returning columns",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "name"}}}).Create(&testUpsertUser{Name: "a"})
+			},
+			want: `INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED."id", INSERTED."name" VALUES (@p1);`,
+		},
+		{
+			name: "returning all",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.Returning{}).Create(&testUpsertUser{Name: "a"})
+			},
+			want: `INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED.* VALUES (@p1);`,
+		},
+		{
+			name: "merge returning columns",
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.OnConflict{UpdateAll: true}, clause.Returning{Columns: []clause.Column{{Name: "name"}}}).Create(&testUpsertUser{ID: 1, Name: "a"})
+			},
+			want: `SET IDENTITY_INSERT "test_upsert_users" ON;MERGE INTO "test_upsert_users" USING (VALUES(@p1,@p2)) AS excluded ("name","id") ON "test_upsert_users"."id" = "excluded"."id" WHEN MATCHED THEN UPDATE SET "name"="excluded"."name" WHEN NOT MATCHED THEN INSERT ("name","id") VALUES ("excluded"."name","excluded"."id") OUTPUT INSERTED."name";SET IDENTITY_INSERT "test_upsert_users" OFF;`,
+		},
+		{
+			name:   "returning all into table variable",
+			config: sqlserver.Config{OutputIntoTableVariable: true},
+			tx: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(clause.Returning{}).Create(&testUpsertUser{Name: "a"})
+			},
+			want: `DECLARE @output0 TABLE ("id" bigint,"name" nvarchar(MAX));INSERT INTO "test_upsert_users" ("name") OUTPUT INSERTED."id", INSERTED."name" INTO @output0 VALUES (@p1);SELECT * FROM @output0;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := tt.tx(openDryRunDB(t, tt.config))
+			if tx.Error != nil {
+				t.Fatalf("failed to create, got error: %v", tx.Error)
+			}
+			if sql := tx.Statement.SQL.String(); sql != tt.want {
+				t.Errorf("expected SQL %q, got %q", tt.want, sql)
+			}
+		})
+	}
+}
+
+func TestCreate_UpsertWithoutModel(t *testing.T) {
+	db := openDryRunDB(t, sqlserver.Config{})
+
+	tx := db.Table("users").Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, UpdateAll: true}).
+		Create(map[string]interface{}{"email": "jinzhu@example.org", "name": "jinzhu"})
+	if tx.Error != nil {
+		t.Fatalf("failed to upsert map, got error: %v", tx.Error)
+	}
+	want := `MERGE INTO "users" USING (VALUES(@p1,@p2)) AS excluded ("email","name") ON "users"."email" = "excluded"."email" WHEN MATCHED THEN UPDATE SET "name"="excluded"."name" WHEN NOT MATCHED THEN INSERT ("email","name") VALUES ("excluded"."email","excluded"."name");`
+	if sql := tx.Statement.SQL.String(); sql != want {
+		t.Errorf("expected SQL %q, got %q", want, sql)
+	}
+
+	tx = db.Table("users").Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{"name": "jinzhu"})
+	if !errors.Is(tx.Error, gorm.ErrPrimaryKeyRequired) {
+		t.Errorf("expected error %v for upsert without conflict columns, got %v", gorm.ErrPrimaryKeyRequired, tx.Error)
+	}
+}
+
+type testUpsertLog struct {
+	Message string
+}
+
+func TestCreate_UpsertWithoutPrimaryKey(t *testing.T) {
+	db := openDryRunDB(t, sqlserver.Config{})
+
+	// without primary keys and OnConflict.Columns the rows can't be matched
+	tx := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&testUpsertLog{Message: "started"})
+	if !errors.Is(tx.Error, gorm.ErrPrimaryKeyRequired) {
+		t.Errorf("expected error %v for upsert without primary keys, got %v", gorm.ErrPrimaryKeyRequired, tx.Error)
+	}
+}
+
+type TestTableDetectTriggers struct {
+	ID   uint
+	Name string
+}
+
+func (*TestTableDetectTriggers) TableName() string { return "test_table_detect_triggers" }
+
+func TestCreate_DetectTriggers(t *testing.T) {
+	db, err := gorm.Open(sqlserver.New(sqlserver.Config{DSN: sqlserverDSN, DetectTriggers: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.Debug()
+	if err = db.Migrator().AutoMigrate(&TestTableDetectTriggers{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err = db.Migrator().DropTable(&TestTableDetectTriggers{}); err != nil {
+			t.Errorf("couldn't drop table test_table_detect_triggers, got error: %v", err)
+		}
+	}()
+
+	if err = db.Create(&TestTableDetectTriggers{Name: "before"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Exec("CREATE TRIGGER trg_test_table_detect_triggers ON test_table_detect_triggers AFTER INSERT AS SET NOCOUNT ON").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the table is cached without triggers until the cache is reset
+	db.Dialector.(*sqlserver.Dialector).ResetTriggerCache("test_table_detect_triggers")
+	row := TestTableDetectTriggers{Name: "after"}
+	if err = db.Create(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+	if row.ID == 0 {
+		t.Errorf("expected generated id")
+	}
+}
@@ -0,0 +1,389 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The types of the objects depending on a column
+const (
+	DependencyDefaultConstraint = "DEFAULT_CONSTRAINT"
+	DependencyCheckConstraint   = "CHECK_CONSTRAINT"
+	DependencyForeignKey        = "FOREIGN_KEY"
+	DependencyPrimaryKey        = "PRIMARY_KEY"
+	DependencyUniqueConstraint  = "UNIQUE_CONSTRAINT"
+	DependencyIndex             = "INDEX"
+	DependencyStatistics        = "STATISTICS"
+)
+
+// ColumnDependency is an object depending on a column, it blocks altering and dropping the column
+type ColumnDependency struct {
+	Type string
+	Name string
+	// Table is the table of the object, foreign keys referencing the column belong to the referencing tables
+	Table string
+	// Recreate reports whether AlterColumn recreates the object after altering the column,
+	// foreign keys and primary keys are recreated as they were, the other objects if the model declares them
+	Recreate bool
+
+	columns           []string
+	referencedTable   string
+	referencedColumns []string
+	onDelete          string
+	onUpdate          string
+	// disabled and notForReplication are the state of foreign keys, they are restored when recreating them
+	disabled          bool
+	notForReplication bool
+	// nonclustered reports whether the primary key is nonclustered, e.g. on tables with another clustered index
+	nonclustered bool
+}
+
+type dependentIndex struct {
+	Name               string
+	IsPrimaryKey       bool
+	IsUniqueConstraint bool
+	TypeDesc           string
+}
+
+type foreignKeyColumn struct {
+	Name                string
+	ParentTable         string
+	ReferencedTable     string
+	ParentColumn        string
+	ReferencedColumn    string
+	OnDelete            string
+	OnUpdate            string
+	IsDisabled          bool
+	IsNotForReplication bool
+}
+
+// ColumnDependencies returns the objects depending on the column name of value's table in the order they are dropped
+// by AlterColumn and DropColumn, it reports what would be dropped and recreated without changing the database
+func (m Migrator) ColumnDependencies(value interface{}, name string) (dependencies []ColumnDependency, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if field := stmt.Schema.LookUpField(name); field != nil {
+				name = field.DBName
+			}
+		}
+		dependencies, err = m.columnDependenciesOf(stmt, name)
+		return err
+	})
+	return
+}
+
+// columnDependenciesOf looks up the objects depending on the column in the catalog views, they are returned in a safe
+// drop order, foreign keys before the keys they reference, constraints and indexes before statistics
+func (m Migrator) columnDependenciesOf(stmt *gorm.Statement, column string) ([]ColumnDependency, error) {
+	var (
+		dependencies []ColumnDependency
+		table        = m.fullTableNameOf(stmt)
+	)
+
+	foreignKeys, err := m.foreignKeysOf(table, column)
+	if err != nil {
+		return nil, err
+	}
+	dependencies = append(dependencies, foreignKeys...)
+
+	var checks []string
+	if err := m.queryDB().Raw(
+		`SELECT cc.name FROM sys.check_constraints cc
+WHERE cc.parent_object_id = OBJECT_ID(?) AND (cc.parent_column_id = COLUMNPROPERTY(cc.parent_object_id, ?, 'ColumnId')
+	OR EXISTS (SELECT 1 FROM sys.sql_expression_dependencies d WHERE d.referencing_id = cc.object_id AND d.referenced_id = cc.parent_object_id
+		AND d.referenced_minor_id = COLUMNPROPERTY(cc.parent_object_id, ?, 'ColumnId')))
+ORDER BY cc.name`,
+		table, column, column,
+	).Scan(&checks).Error; err != nil {
+		return nil, err
+	}
+	for _, check := range checks {
+		dependencies = append(dependencies, ColumnDependency{Type: DependencyCheckConstraint, Name: check, Table: table})
+	}
+
+	var defaults []string
+	if err := m.queryDB().Raw(
+		"SELECT dc.name FROM sys.default_constraints dc WHERE dc.parent_object_id = OBJECT_ID(?) AND dc.parent_column_id = COLUMNPROPERTY(dc.parent_object_id, ?, 'ColumnId')",
+		table, column,
+	).Scan(&defaults).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range defaults {
+		dependencies = append(dependencies, ColumnDependency{Type: DependencyDefaultConstraint, Name: name, Table: table})
+	}
+
+	var indexes []dependentIndex
+	if err := m.queryDB().Raw(
+		`SELECT DISTINCT i.name, i.is_primary_key, i.is_unique_constraint, i.type_desc FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+WHERE i.object_id = OBJECT_ID(?) AND ic.column_id = COLUMNPROPERTY(i.object_id, ?, 'ColumnId')
+ORDER BY i.is_primary_key, i.is_unique_constraint, i.name`,
+		table, column,
+	).Scan(&indexes).Error; err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		dependency := ColumnDependency{Type: DependencyIndex, Name: index.Name, Table: table}
+		switch {
+		case index.IsPrimaryKey:
+			dependency.Type = DependencyPrimaryKey
+			dependency.nonclustered = index.TypeDesc == "NONCLUSTERED"
+			if dependency.columns, err = m.indexColumnsOf(table, index.Name); err != nil {
+				return nil, err
+			}
+		case index.IsUniqueConstraint:
+			dependency.Type = DependencyUniqueConstraint
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	var statistics []string
+	if err := m.queryDB().Raw(
+		`SELECT DISTINCT s.name FROM sys.stats s JOIN sys.stats_columns sc ON sc.object_id = s.object_id AND sc.stats_id = s.stats_id
+WHERE s.object_id = OBJECT_ID(?) AND (s.auto_created = 1 OR s.user_created = 1) AND sc.column_id = COLUMNPROPERTY(s.object_id, ?, 'ColumnId')
+ORDER BY s.name`,
+		table, column,
+	).Scan(&statistics).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range statistics {
+		dependencies = append(dependencies, ColumnDependency{Type: DependencyStatistics, Name: name, Table: table})
+	}
+
+	for idx := range dependencies {
+		dependencies[idx].Recreate = m.isDeclared(stmt, column, dependencies[idx])
+	}
+	return dependencies, nil
+}
+
+// queryDB returns the session the catalog views are queried with, they are queried in dry run mode too, since they
+// only read, so that a dry run reports the dependencies that would be dropped and recreated
+func (m Migrator) queryDB() *gorm.DB {
+	queryTx := m.DB.Session(&gorm.Session{})
+	if m.DB.DryRun {
+		queryTx.DryRun = false
+	}
+	return queryTx
+}
+
+// indexColumnsOf returns the key columns of the index of the table in their order
+func (m Migrator) indexColumnsOf(table, index string) (columns []string, err error) {
+	err = m.queryDB().Raw(
+		`SELECT c.name FROM sys.index_columns ic JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+JOIN sys.indexes i ON i.object_id = ic.object_id AND i.index_id = ic.index_id
+WHERE i.object_id = OBJECT_ID(?) AND i.name = ? AND ic.is_included_column = 0 ORDER BY ic.key_ordinal`,
+		table, index,
+	).Scan(&columns).Error
+	return
+}
+
+// foreignKeysOf returns the foreign keys of the column and the foreign keys referencing it with their definitions
+func (m Migrator) foreignKeysOf(table, column string) ([]ColumnDependency, error) {
+	return m.queryForeignKeys(`fk.object_id IN (
+	SELECT c.constraint_object_id FROM sys.foreign_key_columns c
+	WHERE (c.parent_object_id = OBJECT_ID(?) AND c.parent_column_id = COLUMNPROPERTY(c.parent_object_id, ?, 'ColumnId'))
+		OR (c.referenced_object_id = OBJECT_ID(?) AND c.referenced_column_id = COLUMNPROPERTY(c.referenced_object_id, ?, 'ColumnId'))
+)`, table, column, table, column)
+}
+
+// queryForeignKeys returns the foreign keys matching the condition on sys.foreign_keys fk with their definitions
+func (m Migrator) queryForeignKeys(condition string, vars ...interface{}) ([]ColumnDependency, error) {
+	var rows []foreignKeyColumn
+	if err := m.queryDB().Raw(
+		`SELECT fk.name,
+	OBJECT_SCHEMA_NAME(fk.parent_object_id) + '.' + OBJECT_NAME(fk.parent_object_id) AS parent_table,
+	OBJECT_SCHEMA_NAME(fk.referenced_object_id) + '.' + OBJECT_NAME(fk.referenced_object_id) AS referenced_table,
+	COL_NAME(fkc.parent_object_id, fkc.parent_column_id) AS parent_column,
+	COL_NAME(fkc.referenced_object_id, fkc.referenced_column_id) AS referenced_column,
+	fk.delete_referential_action_desc AS on_delete,
+	fk.update_referential_action_desc AS on_update,
+	fk.is_disabled,
+	fk.is_not_for_replication
+FROM sys.foreign_keys fk
+JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id
+WHERE `+condition+`
+ORDER BY fk.name, fkc.constraint_column_id`,
+		vars...,
+	).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var foreignKeys []ColumnDependency
+	for _, row := range rows {
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != row.Name {
+			foreignKeys = append(foreignKeys, ColumnDependency{
+				Type:              DependencyForeignKey,
+				Name:              row.Name,
+				Table:             row.ParentTable,
+				referencedTable:   row.ReferencedTable,
+				onDelete:          strings.ReplaceAll(row.OnDelete, "_", " "),
+				onUpdate:          strings.ReplaceAll(row.OnUpdate, "_", " "),
+				disabled:          row.IsDisabled,
+				notForReplication: row.IsNotForReplication,
+			})
+		}
+		foreignKey := &foreignKeys[len(foreignKeys)-1]
+		foreignKey.columns = append(foreignKey.columns, row.ParentColumn)
+		foreignKey.referencedColumns = append(foreignKey.referencedColumns, row.ReferencedColumn)
+	}
+	return foreignKeys, nil
+}
+
+// isDeclared reports whether AlterColumn recreates the dependency of the column
+func (m Migrator) isDeclared(stmt *gorm.Statement, column string, dependency ColumnDependency) bool {
+	if dependency.Type == DependencyForeignKey || dependency.Type == DependencyPrimaryKey {
+		return true
+	}
+	if stmt.Schema == nil {
+		return false
+	}
+
+	switch dependency.Type {
+	case DependencyDefaultConstraint:
+		if field := stmt.Schema.LookUpField(column); field != nil {
+			_, ok := m.defaultValueOf(field)
+			return ok
+		}
+	case DependencyCheckConstraint:
+		for name := range stmt.Schema.ParseCheckConstraints() {
+			if strings.EqualFold(name, dependency.Name) {
+				return true
+			}
+		}
+	case DependencyUniqueConstraint:
+		for name := range stmt.Schema.ParseUniqueConstraints() {
+			if strings.EqualFold(name, dependency.Name) {
+				return true
+			}
+		}
+	case DependencyIndex:
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if strings.EqualFold(idx.Name, dependency.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkReferencingColumns returns an error naming the foreign keys referencing the altered column whose columns have
+// another data type than the column now, SQL Server can't recreate them until their columns are altered as well
+func (m Migrator) checkReferencingColumns(table, column string, dependencies []ColumnDependency) error {
+	if m.DB.DryRun {
+		return nil
+	}
+
+	var blocking []string
+	for _, dependency := range dependencies {
+		if dependency.Type != DependencyForeignKey {
+			continue
+		}
+		for idx, referencedColumn := range dependency.referencedColumns {
+			if !strings.EqualFold(referencedColumn, column) {
+				continue
+			}
+			var count int64
+			if err := m.DB.Raw(
+				`SELECT COUNT(*) FROM sys.columns c JOIN sys.columns r ON r.object_id = OBJECT_ID(?) AND r.name = ?
+WHERE r.object_id = OBJECT_ID(?) AND c.object_id = OBJECT_ID(?) AND c.name = ?
+	AND (c.user_type_id <> r.user_type_id OR c.max_length <> r.max_length OR c.precision <> r.precision OR c.scale <> r.scale)`,
+				table, column, dependency.referencedTable, dependency.Table, dependency.columns[idx],
+			).Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				blocking = append(blocking, dependency.Name)
+				break
+			}
+		}
+	}
+	if len(blocking) > 0 {
+		return fmt.Errorf("failed to alter column %s of %s, the columns of the foreign keys %s referencing it have another data type and have to be altered first",
+			column, table, strings.Join(blocking, ", "))
+	}
+	return nil
+}
+
+// dropColumnDependencies drops the dependencies in their order
+func (m Migrator) dropColumnDependencies(dependencies []ColumnDependency) error {
+	for _, dependency := range dependencies {
+		var err error
+		switch dependency.Type {
+		case DependencyIndex:
+			err = m.DB.Exec("DROP INDEX ? ON ?", clause.Column{Name: dependency.Name}, clause.Table{Name: dependency.Table}).Error
+		case DependencyStatistics:
+			err = m.DB.Exec("DROP STATISTICS ?.?", clause.Table{Name: dependency.Table}, clause.Column{Name: dependency.Name}).Error
+		default:
+			err = m.DB.Exec("ALTER TABLE ? DROP CONSTRAINT ?", clause.Table{Name: dependency.Table}, clause.Column{Name: dependency.Name}).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recreateColumnDependencies recreates the dropped dependencies of the column in reverse order, keys before
+// the foreign keys referencing them, the default constraint is recreated by AlterColumn itself
+func (m Migrator) recreateColumnDependencies(value interface{}, dependencies []ColumnDependency) error {
+	for idx := len(dependencies) - 1; idx >= 0; idx-- {
+		dependency := dependencies[idx]
+		if !dependency.Recreate {
+			if dependency.Type != DependencyDefaultConstraint && dependency.Type != DependencyStatistics {
+				m.DB.Logger.Warn(m.DB.Statement.Context, "%s %s on %s was dropped to alter the column and is not declared by the model", dependency.Type, dependency.Name, dependency.Table)
+			}
+			continue
+		}
+
+		var err error
+		switch dependency.Type {
+		case DependencyDefaultConstraint:
+			continue
+		case DependencyIndex:
+			err = m.CreateIndex(value, dependency.Name)
+		case DependencyCheckConstraint, DependencyUniqueConstraint:
+			err = m.CreateConstraint(value, dependency.Name)
+		case DependencyPrimaryKey:
+			primaryKey := "PRIMARY KEY"
+			if dependency.nonclustered {
+				primaryKey += " NONCLUSTERED"
+			}
+			err = m.DB.Exec(
+				"ALTER TABLE ? ADD CONSTRAINT ? "+primaryKey+" ?",
+				clause.Table{Name: dependency.Table}, clause.Column{Name: dependency.Name}, columnsOf(dependency.columns),
+			).Error
+		case DependencyForeignKey:
+			// disabled foreign keys didn't check the existing rows, they are added without checking them and disabled again
+			add := "ADD"
+			if dependency.disabled {
+				add = "WITH NOCHECK ADD"
+			}
+			sql := fmt.Sprintf("ALTER TABLE ? %s CONSTRAINT ? FOREIGN KEY ? REFERENCES ?? ON DELETE %s ON UPDATE %s", add, dependency.onDelete, dependency.onUpdate)
+			if dependency.notForReplication {
+				sql += " NOT FOR REPLICATION"
+			}
+			err = m.DB.Exec(
+				sql, clause.Table{Name: dependency.Table}, clause.Column{Name: dependency.Name}, columnsOf(dependency.columns),
+				clause.Table{Name: dependency.referencedTable}, columnsOf(dependency.referencedColumns),
+			).Error
+			if err == nil && dependency.disabled {
+				err = m.DB.Exec("ALTER TABLE ? NOCHECK CONSTRAINT ?", clause.Table{Name: dependency.Table}, clause.Column{Name: dependency.Name}).Error
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func columnsOf(names []string) []interface{} {
+	columns := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return columns
+}
@@ -1,16 +1,45 @@
 package sqlserver
 
 import (
+	"errors"
+	"regexp"
+	"strings"
+
 	"github.com/microsoft/go-mssqldb"
 
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrNotNullViolated occurs when NULL is written into a NOT NULL column
+	ErrNotNullViolated = errors.New("violates not null constraint")
+	// ErrDataTruncated occurs when string or binary data would be truncated
+	ErrDataTruncated = errors.New("string or binary data would be truncated")
+	// ErrDeadlock occurs when the transaction was chosen as a deadlock victim
+	ErrDeadlock = errors.New("deadlock victim")
+	// ErrLockTimeout occurs when the lock request time out period is exceeded
+	ErrLockTimeout = errors.New("lock request timeout")
+	// ErrSnapshotConflict occurs when a snapshot isolation transaction conflicts with a concurrent update
+	ErrSnapshotConflict = errors.New("snapshot isolation update conflict")
+	// ErrInvalidObjectName occurs when a referenced table or view does not exist
+	ErrInvalidObjectName = errors.New("invalid object name")
+	// ErrCascadePaths occurs when the cascading actions of a foreign key would cause cycles or multiple cascade paths
+	ErrCascadePaths = errors.New("foreign keys may cause cycles or multiple cascade paths")
+)
+
 // The error codes to map mssql errors to gorm errors, here is a reference about error codes for mssql https://learn.microsoft.com/en-us/sql/relational-databases/errors-events/database-engine-events-and-errors?view=sql-server-ver16
 var errCodes = map[int32]error{
 	2627: gorm.ErrDuplicatedKey,
 	2601: gorm.ErrDuplicatedKey,
 	547:  gorm.ErrForeignKeyViolated,
+	515:  ErrNotNullViolated,
+	8152: ErrDataTruncated,
+	2628: ErrDataTruncated,
+	1205: ErrDeadlock,
+	1222: ErrLockTimeout,
+	3960: ErrSnapshotConflict,
+	208:  ErrInvalidObjectName,
+	1785: ErrCascadePaths,
 }
 
 type ErrMessage struct {
@@ -18,14 +47,118 @@ type ErrMessage struct {
 	Message string `json:"Message"`
 }
 
+// Error is a translated mssql error, it wraps the gorm or sqlserver error it is translated to, so errors.Is still works,
+// and carries the details of the mssql error and the names and value parsed from its message
+type Error struct {
+	ErrMessage
+	State      uint8  `json:"State"`
+	Severity   uint8  `json:"Severity"`
+	ProcName   string `json:"ProcName,omitempty"`
+	LineNo     int32  `json:"LineNo,omitempty"`
+	Constraint string `json:"Constraint,omitempty"`
+	Table      string `json:"Table,omitempty"`
+	Column     string `json:"Column,omitempty"`
+	Value      string `json:"Value,omitempty"`
+
+	err   error
+	cause mssql.Error
+	// wrapped is the error passed to Translate if it wraps the mssql error, its message is kept
+	wrapped error
+}
+
+func (e *Error) Error() string {
+	if e.wrapped != nil {
+		return e.err.Error() + ": " + e.wrapped.Error()
+	}
+	return e.err.Error() + ": " + e.Message
+}
+
+// Unwrap returns the gorm or sqlserver error the mssql error is translated to
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// As makes the original mssql.Error available through errors.As
+func (e *Error) As(target interface{}) bool {
+	if mssqlErr, ok := target.(*mssql.Error); ok {
+		*mssqlErr = e.cause
+		return true
+	}
+	return false
+}
+
+var (
+	constraintNameRegexp = regexp.MustCompile(`(?:constraint|unique index) ['"]([^'"]+)['"]`)
+	objectNameRegexp     = regexp.MustCompile(`(?:object|table) ['"]([^'"]+)['"]`)
+	columnNameRegexp     = regexp.MustCompile(`column '([^']+)'`)
+	keyValueRegexp       = regexp.MustCompile(`(?:duplicate key value is \((.*)\)|Truncated value: '(.*)')\.?$`)
+)
+
+func newError(mssqlErr mssql.Error, err error) *Error {
+	e := &Error{
+		ErrMessage: ErrMessage{Number: mssqlErr.Number, Message: mssqlErr.Message},
+		State:      mssqlErr.State,
+		Severity:   mssqlErr.Class,
+		ProcName:   mssqlErr.ProcName,
+		LineNo:     mssqlErr.LineNo,
+		err:        err,
+		cause:      mssqlErr,
+	}
+
+	if matches := constraintNameRegexp.FindStringSubmatch(mssqlErr.Message); len(matches) > 1 {
+		e.Constraint = matches[1]
+	}
+	if matches := objectNameRegexp.FindStringSubmatch(mssqlErr.Message); len(matches) > 1 {
+		e.Table = matches[1]
+	}
+	if matches := columnNameRegexp.FindStringSubmatch(mssqlErr.Message); len(matches) > 1 {
+		e.Column = matches[1]
+	}
+	if matches := keyValueRegexp.FindStringSubmatch(mssqlErr.Message); len(matches) > 2 {
+		e.Value = matches[1] + matches[2]
+	}
+	return e
+}
+
 // Translate it will translate the error to native gorm errors.
+// The number of the mssql error is translated first, if it can't be translated, e.g. 3621 "The statement has been
+// terminated", the errors of its batch are inspected from first to last and the first one that can be translated wins.
+// The message of an error wrapping the mssql error is kept by the translated error.
 func (dialector Dialector) Translate(err error) error {
-	if mssqlErr, ok := err.(mssql.Error); ok {
-		if translatedErr, found := errCodes[mssqlErr.Number]; found {
-			return translatedErr
-		}
+	var mssqlErr mssql.Error
+	if !errors.As(err, &mssqlErr) {
 		return err
 	}
 
+	batchErrs := append([]mssql.Error{mssqlErr}, mssqlErr.All...)
+	for _, batchErr := range batchErrs {
+		if translatedErr := dialector.translateErrorNumber(batchErr); translatedErr != nil {
+			e := newError(batchErr, translatedErr)
+			if _, ok := err.(mssql.Error); !ok {
+				e.wrapped = err
+			}
+			return e
+		}
+	}
+
 	return err
 }
+
+func (dialector Dialector) translateErrorNumber(mssqlErr mssql.Error) error {
+	if dialector.Config != nil {
+		if translatedErr, found := dialector.ErrorTranslations[mssqlErr.Number]; found {
+			return translatedErr
+		}
+	}
+
+	translatedErr, found := errCodes[mssqlErr.Number]
+	if !found {
+		return nil
+	}
+
+	// 547 is raised for both foreign key and check constraint conflicts
+	if mssqlErr.Number == 547 && strings.Contains(mssqlErr.Message, "CHECK constraint") {
+		return gorm.ErrCheckConstraintViolated
+	}
+	return translatedErr
+}
@@ -2,6 +2,8 @@ package sqlserver
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"gorm.io/gorm"
@@ -37,6 +39,46 @@ func TestDialector_Translate(t *testing.T) {
 			args: args{err: mssql.Error{Number: 547}},
 			want: gorm.ErrForeignKeyViolated,
 		},
+		{
+			name: "it should return ErrCheckConstraintViolated if the error number is 547 and a check constraint conflicted",
+			args: args{err: mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the CHECK constraint "chk_age". The conflict occurred in database "gorm", table "dbo.users", column 'age'.`}},
+			want: gorm.ErrCheckConstraintViolated,
+		},
+		{
+			name: "it should return ErrNotNullViolated if the error number is 515",
+			args: args{err: mssql.Error{Number: 515}},
+			want: ErrNotNullViolated,
+		},
+		{
+			name: "it should return ErrDataTruncated if the error number is 8152",
+			args: args{err: mssql.Error{Number: 8152}},
+			want: ErrDataTruncated,
+		},
+		{
+			name: "it should return ErrDataTruncated if the error number is 2628",
+			args: args{err: mssql.Error{Number: 2628}},
+			want: ErrDataTruncated,
+		},
+		{
+			name: "it should return ErrDeadlock if the error number is 1205",
+			args: args{err: mssql.Error{Number: 1205}},
+			want: ErrDeadlock,
+		},
+		{
+			name: "it should return ErrLockTimeout if the error number is 1222",
+			args: args{err: mssql.Error{Number: 1222}},
+			want: ErrLockTimeout,
+		},
+		{
+			name: "it should return ErrSnapshotConflict if the error number is 3960",
+			args: args{err: mssql.Error{Number: 3960}},
+			want: ErrSnapshotConflict,
+		},
+		{
+			name: "it should return ErrInvalidObjectName if the error number is 208",
+			args: args{err: mssql.Error{Number: 208}},
+			want: ErrInvalidObjectName,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -49,3 +91,112 @@ func TestDialector_Translate(t *testing.T) {
 		})
 	}
 }
+
+func TestDialector_TranslateDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		err  mssql.Error
+		want Error
+	}{
+		{
+			name: "unique constraint",
+			err:  mssql.Error{Number: 2627, State: 1, Class: 14, Message: "Violation of UNIQUE KEY constraint 'uni_users_email'. Cannot insert duplicate key in object 'dbo.users'. The duplicate key value is (jinzhu@example.org)."},
+			want: Error{State: 1, Severity: 14, Constraint: "uni_users_email", Table: "dbo.users", Value: "jinzhu@example.org"},
+		},
+		{
+			name: "unique index",
+			err:  mssql.Error{Number: 2601, Message: "Cannot insert duplicate key row in object 'dbo.users' with unique index 'idx_users_email'. The duplicate key value is (jinzhu@example.org)."},
+			want: Error{Constraint: "idx_users_email", Table: "dbo.users", Value: "jinzhu@example.org"},
+		},
+		{
+			name: "foreign key",
+			err:  mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "fk_users_company". The conflict occurred in database "gorm", table "dbo.companies", column 'id'.`},
+			want: Error{Constraint: "fk_users_company", Table: "dbo.companies", Column: "id"},
+		},
+		{
+			name: "not null",
+			err:  mssql.Error{Number: 515, ProcName: "create_user", LineNo: 3, Message: "Cannot insert the value NULL into column 'name', table 'gorm.dbo.users'; column does not allow nulls. INSERT fails."},
+			want: Error{ProcName: "create_user", LineNo: 3, Table: "gorm.dbo.users", Column: "name"},
+		},
+		{
+			name: "truncation",
+			err:  mssql.Error{Number: 2628, Message: "String or binary data would be truncated in table 'gorm.dbo.users', column 'name'. Truncated value: 'jin'."},
+			want: Error{Table: "gorm.dbo.users", Column: "name", Value: "jin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Dialector{}.Translate(tt.err)
+
+			var translatedErr *Error
+			if !errors.As(err, &translatedErr) {
+				t.Fatalf("Translate() expected *Error, got %#v", err)
+			}
+			if !errors.Is(err, errCodes[tt.err.Number]) {
+				t.Errorf("Translate() expected error to wrap %v, got %v", errCodes[tt.err.Number], err)
+			}
+
+			var mssqlErr mssql.Error
+			if !errors.As(err, &mssqlErr) || mssqlErr.Number != tt.err.Number {
+				t.Errorf("Translate() expected error to unwrap to mssql error %v, got %v", tt.err, mssqlErr)
+			}
+
+			got := *translatedErr
+			got.ErrMessage, got.err, got.cause, got.wrapped = ErrMessage{}, nil, mssql.Error{}, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Translate() expected details %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDialector_TranslateWrappedAndBatchErrors(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+	dialector := Dialector{Config: &Config{ErrorTranslations: map[int32]error{50001: errOutOfStock}}}
+
+	duplicated := mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint 'PK_users'."}
+	terminated := mssql.Error{Number: 3621, Message: "The statement has been terminated."}
+	terminated.All = []mssql.Error{duplicated, terminated}
+	lockTimeout := mssql.Error{Number: 1222, Message: "Lock request time out period exceeded."}
+	deadlock := mssql.Error{Number: 1205, Message: "Transaction was deadlocked on lock resources with another process and has been chosen as the deadlock victim."}
+	deadlock.All = []mssql.Error{lockTimeout, deadlock}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "it should translate wrapped errors",
+			err:  fmt.Errorf("create user: %w", duplicated),
+			want: gorm.ErrDuplicatedKey,
+		},
+		{
+			name: "it should translate the first translatable error of a batch",
+			err:  terminated,
+			want: gorm.ErrDuplicatedKey,
+		},
+		{
+			name: "it should prefer the error number over the errors of its batch",
+			err:  deadlock,
+			want: ErrDeadlock,
+		},
+		{
+			name: "it should translate registered error numbers",
+			err:  mssql.Error{Number: 50001, Message: "out of stock"},
+			want: errOutOfStock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := dialector.Translate(tt.err); !errors.Is(err, tt.want) {
+				t.Errorf("Translate() expected error = %v, got error %v", tt.want, err)
+			}
+		})
+	}
+
+	err := dialector.Translate(fmt.Errorf("create user: %w", duplicated))
+	if want := "duplicated key not allowed: create user: mssql: Violation of PRIMARY KEY constraint 'PK_users'."; err.Error() != want {
+		t.Errorf("Translate() expected message %q of wrapped error, got %q", want, err.Error())
+	}
+}
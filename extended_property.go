@@ -0,0 +1,172 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ExtendedPropertyTarget is the object of an extended property, a schema, a table, or a column or an index of a table,
+// Schema defaults to the default schema
+type ExtendedPropertyTarget struct {
+	Schema string
+	Table  string
+	Column string
+	Index  string
+}
+
+// ExtendedPropertyTargetOf returns the target of the table of value, set Column or Index to target their properties
+func (m Migrator) ExtendedPropertyTargetOf(value interface{}) (target ExtendedPropertyTarget, err error) {
+	err = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		target.Schema, target.Table = m.tableNameOf(stmt)
+		return nil
+	})
+	return
+}
+
+// levels returns the level types and names of the target as arguments of the extended property procedures
+func (m Migrator) levels(target ExtendedPropertyTarget) []interface{} {
+	schemaName := target.Schema
+	if schemaName == "" {
+		schemaName = m.DefaultSchema()
+	}
+
+	levels := []interface{}{"SCHEMA", schemaName, nil, nil, nil, nil}
+	if target.Table != "" {
+		levels[2], levels[3] = "TABLE", target.Table
+		switch {
+		case target.Column != "":
+			levels[4], levels[5] = "COLUMN", target.Column
+		case target.Index != "":
+			levels[4], levels[5] = "INDEX", target.Index
+		}
+	}
+	return levels
+}
+
+// GetExtendedProperty returns the extended property name of the target, it is invalid if the property doesn't exist
+func (m Migrator) GetExtendedProperty(target ExtendedPropertyTarget, name string) (value sql.NullString, err error) {
+	err = m.DB.Raw(
+		"SELECT CAST(value AS nvarchar(max)) FROM sys.fn_listextendedproperty(?, ?, ?, ?, ?, ?, ?)",
+		append([]interface{}{name}, m.levels(target)...)...,
+	).Scan(&value).Error
+	return
+}
+
+// GetExtendedProperties returns all extended properties of the target
+func (m Migrator) GetExtendedProperties(target ExtendedPropertyTarget) (map[string]string, error) {
+	var rows []struct {
+		Name  string
+		Value sql.NullString
+	}
+	if err := m.DB.Raw(
+		"SELECT CAST(name AS nvarchar(128)) AS name, CAST(value AS nvarchar(max)) AS value FROM sys.fn_listextendedproperty(NULL, ?, ?, ?, ?, ?, ?)",
+		m.levels(target)...,
+	).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]string, len(rows))
+	for _, row := range rows {
+		properties[row.Name] = row.Value.String
+	}
+	return properties, nil
+}
+
+// SetExtendedProperty adds the extended property name to the target, or updates it if it exists
+func (m Migrator) SetExtendedProperty(target ExtendedPropertyTarget, name, value string) error {
+	current, err := m.GetExtendedProperty(target, name)
+	if err != nil {
+		return err
+	}
+
+	if current.Valid {
+		return m.execExtendedProperty("sp_updateextendedproperty", target, name, value)
+	}
+	return m.execExtendedProperty("sp_addextendedproperty", target, name, value)
+}
+
+// execExtendedProperty adds or updates the extended property name of the target with the procedure
+func (m Migrator) execExtendedProperty(procedure string, target ExtendedPropertyTarget, name, value string) error {
+	return m.DB.Exec(
+		"EXEC "+procedure+" @name = ?, @value = ?, @level0type = ?, @level0name = ?, @level1type = ?, @level1name = ?, @level2type = ?, @level2name = ?",
+		append([]interface{}{name, value}, m.levels(target)...)...,
+	).Error
+}
+
+// DropExtendedProperty drops the extended property name of the target if it exists
+func (m Migrator) DropExtendedProperty(target ExtendedPropertyTarget, name string) error {
+	current, err := m.GetExtendedProperty(target, name)
+	if err != nil || !current.Valid {
+		return err
+	}
+	return m.DB.Exec(
+		"EXEC sp_dropextendedproperty @name = ?, @level0type = ?, @level0name = ?, @level1type = ?, @level1name = ?, @level2type = ?, @level2name = ?",
+		append([]interface{}{name}, m.levels(target)...)...,
+	).Error
+}
+
+// TableWithComment is implemented by models whose tables have a comment, the comment is stored as MS_Description
+// extended property of the table when it's created and kept in sync by AutoMigrate. Alternatively the comment
+// can be set as COMMENT='...' in gorm:table_options.
+type TableWithComment interface {
+	TableComment() string
+}
+
+var tableOptionsCommentRegexp = regexp.MustCompile(`(?i)\s*\bCOMMENT\s*=?\s*N?'((?:[^']|'')*)'`)
+
+// withoutTableOptionsComment removes COMMENT='...' from gorm:table_options, SQL Server doesn't support it in CREATE TABLE,
+// it returns a migrator with the remaining options and the comment
+func (m Migrator) withoutTableOptionsComment() (Migrator, string, bool) {
+	options, ok := m.DB.Get("gorm:table_options")
+	if !ok {
+		return m, "", false
+	}
+
+	str := fmt.Sprint(options)
+	matches := tableOptionsCommentRegexp.FindStringSubmatchIndex(str)
+	if matches == nil {
+		return m, "", false
+	}
+
+	comment := strings.ReplaceAll(str[matches[2]:matches[3]], "''", "'")
+	m.DB = m.DB.Set("gorm:table_options", str[:matches[0]]+str[matches[1]:]).Session(&gorm.Session{})
+	return m, comment, true
+}
+
+// tableCommentOf returns the table comment declared by the model or gorm:table_options
+func (m Migrator) tableCommentOf(value interface{}) (comment string, ok bool) {
+	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if table, isTable := reflect.New(stmt.Schema.ModelType).Interface().(TableWithComment); isTable {
+				comment, ok = table.TableComment(), true
+				return nil
+			}
+		}
+		_, comment, ok = m.withoutTableOptionsComment()
+		return nil
+	})
+	return
+}
+
+// migrateTableComment sets the table comment if it differs from the comment declared for value's table
+func (m Migrator) migrateTableComment(value interface{}) error {
+	comment, ok := m.tableCommentOf(value)
+	if !ok {
+		return nil
+	}
+
+	target, err := m.ExtendedPropertyTargetOf(value)
+	if err != nil {
+		return err
+	}
+	current, err := m.GetExtendedProperty(target, "MS_Description")
+	if err != nil || (current.Valid && current.String == comment) {
+		return err
+	}
+	return m.SetExtendedProperty(target, "MS_Description", comment)
+}
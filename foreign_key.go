@@ -0,0 +1,271 @@
+package sqlserver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// plannedForeignKey is a foreign key of a migrated model, its constraint carries the actions it's created with
+type plannedForeignKey struct {
+	value      interface{}
+	constraint *schema.Constraint
+}
+
+// cascadeGraph has an edge from every referenced table to the tables whose foreign keys cascade its deletes or updates
+type cascadeGraph map[string]map[string]bool
+
+// isCascading reports whether the referential action changes the referencing rows
+func isCascading(action string) bool {
+	switch strings.ToUpper(strings.TrimSpace(action)) {
+	case "CASCADE", "SET NULL", "SET DEFAULT":
+		return true
+	}
+	return false
+}
+
+// reaches reports whether there is a path from table from to table to
+func (g cascadeGraph) reaches(from, to string) bool {
+	visited := map[string]bool{}
+	stack := []string{from}
+	for len(stack) > 0 {
+		table := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for child := range g[table] {
+			if child == to {
+				return true
+			}
+			if !visited[child] {
+				visited[child] = true
+				stack = append(stack, child)
+			}
+		}
+	}
+	return false
+}
+
+// conflictOf describes the cycle or the second cascade path an edge from parent to child would add to the graph,
+// which SQL Server rejects with error 1785, it returns an empty string if the edge is safe
+func (g cascadeGraph) conflictOf(parent, child string) string {
+	if parent == child || g.reaches(child, parent) {
+		return "causes a cycle"
+	}
+
+	tables := map[string]bool{parent: true, child: true}
+	for table, children := range g {
+		tables[table] = true
+		for table := range children {
+			tables[table] = true
+		}
+	}
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	for _, from := range names {
+		if from != parent && !g.reaches(from, parent) {
+			continue
+		}
+		for _, to := range names {
+			if (to == child || g.reaches(child, to)) && g.reaches(from, to) {
+				return fmt.Sprintf("adds a second cascade path from %s to %s", from, to)
+			}
+		}
+	}
+	return ""
+}
+
+func (g cascadeGraph) add(parent, child string) {
+	if g[parent] == nil {
+		g[parent] = map[string]bool{}
+	}
+	g[parent][child] = true
+}
+
+// tableKeyOf returns the lower cased, schema qualified name of the table, which the cascade graphs are keyed by
+func (m Migrator) tableKeyOf(table string) string {
+	schemaName, tableName := m.tableNameOf(&gorm.Statement{Table: table})
+	return strings.ToLower(schemaName + "." + tableName)
+}
+
+// planForeignKeys collects the foreign keys of the models before any table is created, and checks that their cascading
+// actions don't cause cycles or multiple cascade paths, together with the cascading foreign keys of the database.
+// The offending foreign keys are reported in one ErrCascadePaths, or created with NO ACTION instead and logged as
+// warnings if Config.DowngradeCascadePaths is set.
+func (m Migrator) planForeignKeys(values []interface{}) ([]plannedForeignKey, error) {
+	if m.DB.DisableForeignKeyConstraintWhenMigrating || m.DB.IgnoreRelationshipsWhenMigrating {
+		return nil, nil
+	}
+
+	var (
+		foreignKeys []plannedForeignKey
+		planned     = map[string]bool{}
+	)
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if stmt.Schema == nil {
+				return nil
+			}
+
+			for _, rel := range stmt.Schema.Relationships.Relations {
+				if rel.Field.IgnoreMigration {
+					continue
+				}
+				constraint := rel.ParseConstraint()
+				if constraint == nil || constraint.Schema != stmt.Schema {
+					continue
+				}
+				if key := m.tableKeyOf(stmt.Table) + "." + strings.ToLower(constraint.Name); !planned[key] {
+					planned[key] = true
+					foreignKeys = append(foreignKeys, plannedForeignKey{value: value, constraint: constraint})
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// the foreign keys are checked by table and name, so the plan depends neither on the order of the models
+	// nor on the iteration order of their relationships
+	sort.SliceStable(foreignKeys, func(i, j int) bool {
+		ci, cj := foreignKeys[i].constraint, foreignKeys[j].constraint
+		if ci.Schema.Table != cj.Schema.Table {
+			return ci.Schema.Table < cj.Schema.Table
+		}
+		return ci.Name < cj.Name
+	})
+
+	var (
+		conflicts                []string
+		deleteGraph, updateGraph = cascadeGraph{}, cascadeGraph{}
+		downgrade                = configOf(m.DB).DowngradeCascadePaths
+	)
+
+	// the cascade paths of the existing foreign keys count as well, unless they are foreign keys of the models,
+	// they are only looked up if a foreign key of the models cascades
+	var existing []ColumnDependency
+	for _, foreignKey := range foreignKeys {
+		if isCascading(foreignKey.constraint.OnDelete) || isCascading(foreignKey.constraint.OnUpdate) {
+			var err error
+			if existing, err = m.queryForeignKeys("fk.delete_referential_action <> 0 OR fk.update_referential_action <> 0"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	for _, foreignKey := range existing {
+		if planned[m.tableKeyOf(foreignKey.Table)+"."+strings.ToLower(foreignKey.Name)] {
+			continue
+		}
+		parent, child := m.tableKeyOf(foreignKey.referencedTable), m.tableKeyOf(foreignKey.Table)
+		if isCascading(foreignKey.onDelete) {
+			deleteGraph.add(parent, child)
+		}
+		if isCascading(foreignKey.onUpdate) {
+			updateGraph.add(parent, child)
+		}
+	}
+
+	for idx, foreignKey := range foreignKeys {
+		var (
+			constraint = *foreignKey.constraint
+			parent     = m.tableKeyOf(constraint.ReferenceSchema.Table)
+			child      = m.tableKeyOf(constraint.Schema.Table)
+		)
+		for _, action := range []struct {
+			name  string
+			value *string
+			graph cascadeGraph
+		}{
+			{name: "ON DELETE", value: &constraint.OnDelete, graph: deleteGraph},
+			{name: "ON UPDATE", value: &constraint.OnUpdate, graph: updateGraph},
+		} {
+			if !isCascading(*action.value) {
+				continue
+			}
+			if conflict := action.graph.conflictOf(parent, child); conflict != "" {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"%s on %s %s %s referencing %s %s",
+					constraint.Name, constraint.Schema.Table, action.name, strings.ToUpper(*action.value), constraint.ReferenceSchema.Table, conflict,
+				))
+				*action.value = ""
+				continue
+			}
+			action.graph.add(parent, child)
+		}
+		foreignKeys[idx].constraint = &constraint
+	}
+
+	if len(conflicts) > 0 {
+		if !downgrade {
+			return nil, fmt.Errorf("%w: %s", ErrCascadePaths, strings.Join(conflicts, "; "))
+		}
+		for _, conflict := range conflicts {
+			m.DB.Logger.Warn(m.DB.Statement.Context, "%s, the foreign key is created with NO ACTION", conflict)
+		}
+	}
+	return foreignKeys, nil
+}
+
+// createForeignKeys creates the planned foreign keys, the existing ones are skipped if checkExisting is set.
+// Both the table and the referenced table are qualified with the default schema, unless they name their own schema.
+func (m Migrator) createForeignKeys(foreignKeys []plannedForeignKey, checkExisting bool) error {
+	for _, foreignKey := range foreignKeys {
+		if checkExisting && m.DB.Migrator().HasConstraint(foreignKey.value, foreignKey.constraint.Name) {
+			continue
+		}
+
+		if err := m.RunWithValue(foreignKey.value, func(stmt *gorm.Statement) error {
+			sql, vars := m.buildForeignKey(stmt, foreignKey.constraint)
+			return m.DB.Exec(sql, vars...).Error
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildForeignKey builds the statement adding the foreign key to the statement's table like gorm's Constraint.Build,
+// with the referenced table qualified as well
+func (m Migrator) buildForeignKey(stmt *gorm.Statement, constraint *schema.Constraint) (sql string, vars []interface{}) {
+	sql = "ALTER TABLE ? ADD CONSTRAINT ? FOREIGN KEY ? REFERENCES ??"
+	if constraint.OnDelete != "" {
+		sql += " ON DELETE " + constraint.OnDelete
+	}
+	if constraint.OnUpdate != "" {
+		sql += " ON UPDATE " + constraint.OnUpdate
+	}
+
+	columns := make([]interface{}, 0, len(constraint.ForeignKeys))
+	for _, field := range constraint.ForeignKeys {
+		columns = append(columns, clause.Column{Name: field.DBName})
+	}
+	references := make([]interface{}, 0, len(constraint.References))
+	for _, field := range constraint.References {
+		references = append(references, clause.Column{Name: field.DBName})
+	}
+
+	vars = []interface{}{
+		clause.Table{Name: m.fullTableNameOf(stmt)},
+		clause.Table{Name: constraint.Name},
+		columns,
+		clause.Table{Name: m.fullTableNameOf(&gorm.Statement{Table: constraint.ReferenceSchema.Table})},
+		references,
+	}
+	return
+}
+
+// withoutForeignKeys returns a migrator that creates tables without their foreign keys, which are created once all
+// tables exist, so the order of the migrated models doesn't matter
+func (m Migrator) withoutForeignKeys() Migrator {
+	m.DB = m.DB.Session(&gorm.Session{})
+	m.DB.DisableForeignKeyConstraintWhenMigrating = true
+	return m
+}
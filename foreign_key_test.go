@@ -0,0 +1,141 @@
+package sqlserver_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gorm.io/driver/sqlserver"
+)
+
+type testCascadeCustomer struct {
+	ID   uint
+	Name string
+}
+
+type testCascadeAccount struct {
+	ID         uint
+	CustomerID uint
+	Customer   testCascadeCustomer `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type testCascadeInvoice struct {
+	ID         uint
+	CustomerID uint
+	Customer   testCascadeCustomer `gorm:"constraint:OnDelete:CASCADE"`
+	AccountID  uint
+	Account    testCascadeAccount `gorm:"constraint:OnDelete:CASCADE,OnUpdate:CASCADE"`
+}
+
+type testCascadeCategory struct {
+	ID       uint
+	ParentID *uint
+	Parent   *testCascadeCategory `gorm:"constraint:OnDelete:SET NULL"`
+}
+
+func TestMigrator_CascadePaths(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{}).Session(&gorm.Session{Logger: recorder})
+
+	err := db.Migrator().CreateTable(&testCascadeInvoice{}, &testCascadeAccount{}, &testCascadeCustomer{})
+	if !errors.Is(err, sqlserver.ErrCascadePaths) || !strings.Contains(err.Error(), "fk_test_cascade_invoices_customer on test_cascade_invoices ON DELETE CASCADE") {
+		t.Errorf("expected multiple cascade paths error, got %v", err)
+	}
+	for _, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, "CREATE TABLE") {
+			t.Errorf("expected no table to be created, got %s", sql)
+		}
+	}
+
+	err = db.Migrator().CreateTable(&testCascadeCategory{})
+	if !errors.Is(err, sqlserver.ErrCascadePaths) || !strings.Contains(err.Error(), "causes a cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestMigrator_ExistingCascadePaths(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open(sqlserverDSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm := db.Debug().Migrator()
+	defer func() {
+		if err = dm.DropTable(&testCascadeInvoice{}, &testCascadeAccount{}, &testCascadeCustomer{}); err != nil {
+			t.Errorf("couldn't drop the cascade tables, got error: %v", err)
+		}
+	}()
+
+	if err = dm.CreateTable(&testCascadeAccount{}, &testCascadeCustomer{}); err != nil {
+		t.Fatalf("failed to create tables, got error: %v", err)
+	}
+	// the cascading foreign key of the existing accounts adds the second cascade path from the customers
+	err = dm.CreateTable(&testCascadeInvoice{})
+	if !errors.Is(err, sqlserver.ErrCascadePaths) || !strings.Contains(err.Error(), "adds a second cascade path") {
+		t.Errorf("expected multiple cascade paths error, got %v", err)
+	}
+	if dm.HasTable(&testCascadeInvoice{}) {
+		t.Errorf("expected table test_cascade_invoices not to be created")
+	}
+}
+
+func TestMigrator_DowngradeCascadePaths(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{DowngradeCascadePaths: true}).Session(&gorm.Session{Logger: recorder})
+
+	if err := db.Migrator().CreateTable(&testCascadeInvoice{}, &testCascadeAccount{}, &testCascadeCustomer{}); err != nil {
+		t.Fatalf("failed to create tables, got error: %v", err)
+	}
+
+	var statements []string
+	for _, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, "CREATE TABLE") || strings.HasPrefix(sql, "ALTER TABLE") {
+			statements = append(statements, sql)
+		}
+	}
+	want := []string{
+		`CREATE TABLE "dbo"."test_cascade_invoices"`,
+		`CREATE TABLE "dbo"."test_cascade_accounts"`,
+		`CREATE TABLE "dbo"."test_cascade_customers"`,
+		`ALTER TABLE "dbo"."test_cascade_accounts" ADD CONSTRAINT "fk_test_cascade_accounts_customer" FOREIGN KEY ("customer_id") REFERENCES "dbo"."test_cascade_customers"("id") ON DELETE CASCADE`,
+		`ALTER TABLE "dbo"."test_cascade_invoices" ADD CONSTRAINT "fk_test_cascade_invoices_account" FOREIGN KEY ("account_id") REFERENCES "dbo"."test_cascade_accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE`,
+		`ALTER TABLE "dbo"."test_cascade_invoices" ADD CONSTRAINT "fk_test_cascade_invoices_customer" FOREIGN KEY ("customer_id") REFERENCES "dbo"."test_cascade_customers"("id")`,
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("expected statements %v, got %v", want, statements)
+	}
+	for idx, sql := range statements {
+		matched := strings.HasPrefix(sql, want[idx])
+		if strings.HasPrefix(sql, "ALTER TABLE") {
+			matched = sql == want[idx]
+		}
+		if !matched {
+			t.Errorf("expected statement %s, got %s", want[idx], sql)
+		}
+	}
+}
+
+func TestMigrator_ForeignKeysInDefaultSchema(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{MigratorDefaultSchema: "sales"}).Session(&gorm.Session{Logger: recorder})
+
+	if err := db.Migrator().CreateTable(&testCascadeAccount{}, &testCascadeCustomer{}); err != nil {
+		t.Fatalf("failed to create tables, got error: %v", err)
+	}
+
+	want := `ALTER TABLE "sales"."test_cascade_accounts" ADD CONSTRAINT "fk_test_cascade_accounts_customer" FOREIGN KEY ("customer_id") REFERENCES "sales"."test_cascade_customers"("id") ON DELETE CASCADE`
+	var found bool
+	for _, sql := range recorder.sqls {
+		if strings.HasPrefix(sql, "ALTER TABLE") {
+			if sql != want {
+				t.Errorf("expected statement %s, got %s", want, sql)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected statement %s, got %v", want, recorder.sqls)
+	}
+}
@@ -0,0 +1,341 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Full-text indexes are declared by index tags of class FULLTEXT, e.g.
+//
+//	Name        string `gorm:"index:ft_products,class:FULLTEXT,catalog:ft_catalog,change_tracking:auto,language:English"`
+//	Description string `gorm:"index:ft_products,class:FULLTEXT,language:1033"`
+//
+// SQL Server supports a single unnamed full-text index per table, so all FULLTEXT indexes of a model are created as
+// one full-text index. LANGUAGE is set per column, the other settings apply to the full-text index:
+// CATALOG is the full-text catalog, created if missing, KEY_INDEX the unique key index, defaults to the primary key index,
+// CHANGE_TRACKING is AUTO, MANUAL or OFF and STOPLIST is OFF, SYSTEM or the name of a stoplist.
+
+// changeTrackings are the supported values of the change_tracking setting of full-text indexes
+var changeTrackings = map[string]bool{"AUTO": true, "MANUAL": true, "OFF": true}
+
+// isFullTextIndex reports whether idx is a full-text index
+func isFullTextIndex(idx *schema.Index) bool {
+	return idx != nil && strings.EqualFold(idx.Class, "FULLTEXT")
+}
+
+// fullTextIndexesOf returns the full-text indexes of the model
+func fullTextIndexesOf(stmt *gorm.Statement) (indexes []*schema.Index) {
+	if stmt.Schema == nil {
+		return nil
+	}
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		if isFullTextIndex(idx) {
+			indexes = append(indexes, idx)
+		}
+	}
+	return indexes
+}
+
+// CreateFullTextCatalog creates the full-text catalog name, optionally as default catalog of the database
+func (m Migrator) CreateFullTextCatalog(name string, asDefault bool) error {
+	sql := "CREATE FULLTEXT CATALOG ?"
+	if asDefault {
+		sql += " AS DEFAULT"
+	}
+	return m.DB.Exec(sql, clause.Column{Name: name}).Error
+}
+
+// HasFullTextCatalog reports whether the full-text catalog name exists
+func (m Migrator) HasFullTextCatalog(name string) bool {
+	var count int
+	_ = m.DB.Raw("SELECT count(*) FROM sys.fulltext_catalogs WHERE name = ?", name).Scan(&count)
+	return count > 0
+}
+
+// DropFullTextCatalog drops the full-text catalog name if it exists
+func (m Migrator) DropFullTextCatalog(name string) error {
+	if !m.HasFullTextCatalog(name) {
+		return nil
+	}
+	return m.DB.Exec("DROP FULLTEXT CATALOG ?", clause.Column{Name: name}).Error
+}
+
+// CreateFullTextIndex creates the full-text index of the model from its FULLTEXT index tags
+func (m Migrator) CreateFullTextIndex(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		indexes := fullTextIndexesOf(stmt)
+		if len(indexes) == 0 {
+			return fmt.Errorf("failed to create full-text index on %s, no FULLTEXT index tags found", stmt.Table)
+		}
+
+		var (
+			settings = map[string]string{}
+			columns  []interface{}
+			added    = map[string]bool{}
+		)
+		for _, idx := range indexes {
+			for key, setting := range m.indexSettings(stmt, idx) {
+				if _, ok := settings[key]; !ok {
+					settings[key] = setting
+				}
+			}
+			for _, opt := range idx.Fields {
+				if added[opt.DBName] {
+					continue
+				}
+				added[opt.DBName] = true
+
+				column := stmt.Quote(opt.DBName)
+				if language := m.indexFieldSettings(stmt, idx, opt)["LANGUAGE"]; language != "" {
+					column += " LANGUAGE " + languageTerm(language)
+				}
+				columns = append(columns, clause.Expr{SQL: column})
+			}
+		}
+
+		keyIndex := strings.TrimSpace(settings["KEY_INDEX"])
+		if keyIndex == "" {
+			keyIndex = m.primaryKeyIndexOf(stmt)
+		}
+		if keyIndex == "" {
+			return fmt.Errorf("failed to create full-text index on %s, a unique key index is required", stmt.Table)
+		}
+
+		createSQL := "CREATE FULLTEXT INDEX ON ?? KEY INDEX ?"
+		values := []interface{}{clause.Table{Name: m.fullTableNameOf(stmt)}, columns, clause.Column{Name: keyIndex}}
+
+		if catalog := strings.TrimSpace(settings["CATALOG"]); catalog != "" {
+			if !m.DB.DryRun && !m.HasFullTextCatalog(catalog) {
+				if err := m.CreateFullTextCatalog(catalog, false); err != nil {
+					return err
+				}
+			}
+			createSQL += " ON ?"
+			values = append(values, clause.Column{Name: catalog})
+		}
+
+		var options []string
+		if changeTracking, ok := settings["CHANGE_TRACKING"]; ok {
+			changeTracking = strings.ToUpper(strings.TrimSpace(changeTracking))
+			if !changeTrackings[changeTracking] {
+				return fmt.Errorf("invalid change_tracking %s of full-text index on %s", changeTracking, stmt.Table)
+			}
+			options = append(options, "CHANGE_TRACKING = "+changeTracking)
+		}
+		if stoplist := strings.TrimSpace(settings["STOPLIST"]); stoplist != "" {
+			if upper := strings.ToUpper(stoplist); upper == "OFF" || upper == "SYSTEM" {
+				stoplist = upper
+			} else {
+				stoplist = stmt.Quote(stoplist)
+			}
+			options = append(options, "STOPLIST = "+stoplist)
+		}
+		if len(options) > 0 {
+			createSQL += " WITH " + strings.Join(options, ", ")
+		}
+
+		return m.DB.Exec(createSQL, values...).Error
+	})
+}
+
+// HasFullTextIndex reports whether the table of the model has a full-text index
+func (m Migrator) HasFullTextIndex(value interface{}) bool {
+	var count int
+	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Raw(
+			"SELECT count(*) FROM sys.fulltext_indexes WHERE object_id = OBJECT_ID(?)", m.fullTableNameOf(stmt),
+		).Scan(&count).Error
+	})
+	return count > 0
+}
+
+// DropFullTextIndex drops the full-text index of the table of the model
+func (m Migrator) DropFullTextIndex(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.DB.Exec("DROP FULLTEXT INDEX ON ?", clause.Table{Name: m.fullTableNameOf(stmt)}).Error
+	})
+}
+
+// primaryKeyIndexOf returns the name of the primary key index of the table
+func (m Migrator) primaryKeyIndexOf(stmt *gorm.Statement) (name string) {
+	if m.DB.DryRun {
+		return
+	}
+	m.DB.Raw(
+		"SELECT name FROM sys.indexes WHERE object_id = OBJECT_ID(?) AND is_primary_key = 1", m.fullTableNameOf(stmt),
+	).Scan(&name)
+	return
+}
+
+// languageTerm renders a full-text language, LCIDs are kept as they are, language names are quoted
+func languageTerm(language string) string {
+	language = strings.TrimSpace(language)
+	if _, err := strconv.Atoi(language); err == nil || strings.HasPrefix(language, "0x") {
+		return language
+	}
+	return "'" + strings.ReplaceAll(language, "'", "''") + "'"
+}
+
+// Contains is a CONTAINS predicate matching the full-text indexed Columns with the search condition Query,
+// all full-text indexed columns are searched if Columns is empty, e.g.
+//
+//	db.Where(sqlserver.Contains{Columns: []string{"name"}, Query: `"bike*" OR "helmet"`}).Find(&products)
+type Contains struct {
+	Columns  []string
+	Query    string
+	Language string
+}
+
+// Build builds the CONTAINS predicate
+func (c Contains) Build(builder clause.Builder) {
+	buildFullTextPredicate(builder, "CONTAINS", c.Columns, c.Query, c.Language)
+}
+
+// FreeText is a FREETEXT predicate matching the meaning of the free text Query in the full-text indexed Columns,
+// all full-text indexed columns are searched if Columns is empty
+type FreeText struct {
+	Columns  []string
+	Query    string
+	Language string
+}
+
+// Build builds the FREETEXT predicate
+func (f FreeText) Build(builder clause.Builder) {
+	buildFullTextPredicate(builder, "FREETEXT", f.Columns, f.Query, f.Language)
+}
+
+func buildFullTextPredicate(builder clause.Builder, function string, columns []string, query, language string) {
+	builder.WriteString(function)
+	builder.WriteByte('(')
+	writeFullTextColumns(builder, columns)
+	builder.WriteString(", ")
+	builder.AddVar(builder, query)
+	if language != "" {
+		builder.WriteString(", LANGUAGE " + languageTerm(language))
+	}
+	builder.WriteByte(')')
+}
+
+func writeFullTextColumns(builder clause.Builder, columns []string) {
+	switch len(columns) {
+	case 0:
+		builder.WriteByte('*')
+	case 1:
+		builder.WriteQuoted(clause.Column{Name: columns[0]})
+	default:
+		builder.WriteByte('(')
+		for idx, column := range columns {
+			if idx > 0 {
+				builder.WriteByte(',')
+			}
+			builder.WriteQuoted(clause.Column{Name: column})
+		}
+		builder.WriteByte(')')
+	}
+}
+
+// ContainsTable joins the ranked results of CONTAINSTABLE, order by Rank to get the best matches first, e.g.
+//
+//	search := sqlserver.ContainsTable{Table: "products", Columns: []string{"name"}, Query: "bike", TopN: 10}
+//	db.Joins("?", search).Order(clause.OrderByColumn{Column: search.Rank(), Desc: true}).Find(&products)
+type ContainsTable struct {
+	// Table is the searched table, defaults to the current table
+	Table string
+	// Columns are the searched columns, defaults to all full-text indexed columns
+	Columns  []string
+	Query    string
+	Language string
+	// TopN limits the results to the TopN best matches if positive
+	TopN int
+	// Alias is the alias of the results, defaults to ft
+	Alias string
+	// Key is the column joined with the KEY of the results, defaults to the primary key of the current table
+	Key clause.Column
+}
+
+// Build builds the INNER JOIN of CONTAINSTABLE
+func (t ContainsTable) Build(builder clause.Builder) {
+	buildFullTextTable(builder, "CONTAINSTABLE", t.Table, t.Columns, t.Query, t.Language, t.TopN, t.alias(), t.Key)
+}
+
+// Rank returns the RANK column of the results
+func (t ContainsTable) Rank() clause.Column {
+	return clause.Column{Table: t.alias(), Name: "RANK"}
+}
+
+func (t ContainsTable) alias() string {
+	if t.Alias == "" {
+		return "ft"
+	}
+	return t.Alias
+}
+
+// FreeTextTable joins the ranked results of FREETEXTTABLE, order by Rank to get the best matches first
+type FreeTextTable struct {
+	// Table is the searched table, defaults to the current table
+	Table string
+	// Columns are the searched columns, defaults to all full-text indexed columns
+	Columns  []string
+	Query    string
+	Language string
+	// TopN limits the results to the TopN best matches if positive
+	TopN int
+	// Alias is the alias of the results, defaults to ft
+	Alias string
+	// Key is the column joined with the KEY of the results, defaults to the primary key of the current table
+	Key clause.Column
+}
+
+// Build builds the INNER JOIN of FREETEXTTABLE
+func (t FreeTextTable) Build(builder clause.Builder) {
+	buildFullTextTable(builder, "FREETEXTTABLE", t.Table, t.Columns, t.Query, t.Language, t.TopN, t.alias(), t.Key)
+}
+
+// Rank returns the RANK column of the results
+func (t FreeTextTable) Rank() clause.Column {
+	return clause.Column{Table: t.alias(), Name: "RANK"}
+}
+
+func (t FreeTextTable) alias() string {
+	if t.Alias == "" {
+		return "ft"
+	}
+	return t.Alias
+}
+
+func buildFullTextTable(builder clause.Builder, function, table string, columns []string, query, language string, topN int, alias string, key clause.Column) {
+	builder.WriteString("INNER JOIN " + function + "(")
+	if table == "" {
+		builder.WriteQuoted(clause.Table{Name: clause.CurrentTable})
+	} else {
+		builder.WriteQuoted(clause.Table{Name: table})
+	}
+	builder.WriteString(", ")
+	writeFullTextColumns(builder, columns)
+	builder.WriteString(", ")
+	builder.AddVar(builder, query)
+	if language != "" {
+		builder.WriteString(", LANGUAGE " + languageTerm(language))
+	}
+	if topN > 0 {
+		builder.WriteString(", " + strconv.Itoa(topN))
+	}
+	builder.WriteString(") AS ")
+	builder.WriteQuoted(alias)
+
+	if key.Name == "" {
+		key = clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}
+		if table != "" {
+			key.Table = table
+		}
+	}
+	builder.WriteString(" ON ")
+	builder.WriteQuoted(clause.Column{Table: alias, Name: "KEY"})
+	builder.WriteString(" = ")
+	builder.WriteQuoted(key)
+}
@@ -0,0 +1,69 @@
+package sqlserver_test
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+type testFullTextProduct struct {
+	ID          uint
+	Name        string `gorm:"size:256;index:ft_products,class:FULLTEXT,catalog:ft_catalog,change_tracking:auto,key_index:pk_products,language:English"`
+	Description string `gorm:"index:ft_products,class:FULLTEXT,language:1033"`
+}
+
+func TestMigrator_CreateFullTextIndex(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{}).Session(&gorm.Session{Logger: recorder})
+
+	if err := db.Migrator().CreateIndex(&testFullTextProduct{}, "ft_products"); err != nil {
+		t.Fatalf("failed to create full-text index, got error: %v", err)
+	}
+	want := `CREATE FULLTEXT INDEX ON "dbo"."test_full_text_products"("name" LANGUAGE 'English',"description" LANGUAGE 1033) KEY INDEX "pk_products" ON "ft_catalog" WITH CHANGE_TRACKING = AUTO`
+	if len(recorder.sqls) == 0 || recorder.sqls[len(recorder.sqls)-1] != want {
+		t.Errorf("expected SQL %s, got %v", want, recorder.sqls)
+	}
+}
+
+func TestFullTextClauses(t *testing.T) {
+	db := openDryRunDB(t, sqlserver.Config{})
+	search := sqlserver.ContainsTable{Columns: []string{"name", "description"}, Query: "bike", TopN: 10}
+
+	tests := []struct {
+		name string
+		tx   *gorm.DB
+		want string
+	}{
+		{
+			name: "contains",
+			tx:   db.Where(sqlserver.Contains{Columns: []string{"name"}, Query: `"bike*"`}).Find(&[]testFullTextProduct{}),
+			want: `SELECT * FROM "test_full_text_products" WHERE CONTAINS("name", @p1)`,
+		},
+		{
+			name: "freetext all columns with language",
+			tx:   db.Where(sqlserver.FreeText{Query: "mountain bike", Language: "English"}).Find(&[]testFullTextProduct{}),
+			want: `SELECT * FROM "test_full_text_products" WHERE FREETEXT(*, @p1, LANGUAGE 'English')`,
+		},
+		{
+			name: "containstable ordered by rank",
+			tx:   db.Joins("?", search).Order(clause.OrderByColumn{Column: search.Rank(), Desc: true}).Find(&[]testFullTextProduct{}),
+			want: `SELECT "test_full_text_products"."id","test_full_text_products"."name","test_full_text_products"."description" FROM "test_full_text_products" INNER JOIN CONTAINSTABLE("test_full_text_products", ("name","description"), @p1, 10) AS "ft" ON "ft"."KEY" = "test_full_text_products"."id" ORDER BY "ft"."RANK" DESC`,
+		},
+		{
+			name: "freetexttable with alias",
+			tx: db.Joins("?", sqlserver.FreeTextTable{Table: "test_full_text_products", Query: "bike", Alias: "r"}).
+				Find(&[]testFullTextProduct{}),
+			want: `SELECT "test_full_text_products"."id","test_full_text_products"."name","test_full_text_products"."description" FROM "test_full_text_products" INNER JOIN FREETEXTTABLE("test_full_text_products", *, @p1) AS "r" ON "r"."KEY" = "test_full_text_products"."id"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tx.Statement.SQL.String(); got != tt.want {
+				t.Errorf("expected SQL %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// identityOf returns the seed and the increment of an auto increment field, declared by the autoIncrementSeed and
+// autoIncrementIncrement tags, e.g.
+//
+//	ID uint `gorm:"primaryKey;autoIncrementSeed:1000000"`
+//
+// seeds that aren't integers like 1e6 are reported as error, the seed is 1 then
+func identityOf(field *schema.Field) (seed, increment int64, err error) {
+	seed, increment = 1, field.AutoIncrementIncrement
+	if value, ok := field.TagSettings["AUTOINCREMENTSEED"]; ok {
+		n, parseErr := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if parseErr != nil {
+			err = fmt.Errorf("invalid autoIncrementSeed %s of column %s: %w", value, field.DBName, parseErr)
+		} else {
+			seed = n
+		}
+	}
+	if increment == 0 {
+		increment = schema.DefaultAutoIncrementIncrement
+	}
+	return
+}
+
+// identityChanged reports whether the identity of the column differs from the seed and increment of the field,
+// SQL Server can't alter them, the column has to be recreated
+func identityChanged(field *schema.Field, columnType gorm.ColumnType) bool {
+	info, ok := columnType.(*ColumnInfo)
+	if !ok || !field.AutoIncrement || !info.IdentitySeed.Valid {
+		return false
+	}
+	seed, increment, err := identityOf(field)
+	return err == nil && (info.IdentitySeed.Int64 != seed || info.IdentityIncrement.Int64 != increment)
+}
+
+// ReseedIdentity sets the current identity value of value's table, the next row gets value plus the increment,
+// or value itself if no rows have been inserted since the table was created or truncated
+func (m Migrator) ReseedIdentity(value interface{}, identity int64) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		return m.checkIdent(m.fullTableNameOf(stmt), identity)
+	})
+}
+
+// checkIdent reseeds the identity of the table, DBCC CHECKIDENT takes the table name as a literal, so every part of the
+// name is quoted like QUOTENAME does before it's embedded into the literal
+func (m Migrator) checkIdent(table string, identity int64) error {
+	parts := strings.Split(table, ".")
+	for idx, part := range parts {
+		parts[idx] = "[" + strings.ReplaceAll(part, "]", "]]") + "]"
+	}
+	name := strings.ReplaceAll(strings.Join(parts, "."), "'", "''")
+	return m.DB.Exec(fmt.Sprintf("DBCC CHECKIDENT('%s', RESEED, %d) WITH NO_INFOMSGS", name, identity)).Error
+}
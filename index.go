@@ -0,0 +1,353 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// indexOptions are the SQL Server specific settings of an index, they are set in the index tag, e.g.
+//
+//	Name  string `gorm:"index:idx_name,type:NONCLUSTERED,include:email age,fillfactor:80,data_compression:page,online"`
+//
+// INCLUDE lists the included columns separated by spaces, PAD_INDEX, ONLINE and SORT_IN_TEMPDB are switched on by their key.
+// Columnstore indexes are created with type CLUSTERED COLUMNSTORE or NONCLUSTERED COLUMNSTORE, e.g.
+//
+//	Amount float64 `gorm:"index:ncci_sales,type:NONCLUSTERED COLUMNSTORE,where:amount > 0,compression_delay:10"`
+//
+// a clustered columnstore index has no columns, it may be tagged on any field
+type indexOptions struct {
+	Type            string
+	Include         []string
+	FillFactor      int
+	PadIndex        bool
+	DataCompression string
+	Online          bool
+	SortInTempDB    bool
+	MaxDOP          int
+	// CompressionDelay is the minutes rows stay in the delta store of a columnstore index
+	CompressionDelay int
+}
+
+// indexTypes are the index types supported in the type setting of the index tag, mapped to their type_desc in sys.indexes
+var indexTypes = map[string]string{
+	"CLUSTERED":                "CLUSTERED",
+	"NONCLUSTERED":             "NONCLUSTERED",
+	"CLUSTERED COLUMNSTORE":    "CLUSTERED COLUMNSTORE",
+	"NONCLUSTERED COLUMNSTORE": "NONCLUSTERED COLUMNSTORE",
+	"COLUMNSTORE":              "NONCLUSTERED COLUMNSTORE",
+}
+
+// dataCompressions are the supported values of the data_compression setting of rowstore indexes
+var dataCompressions = map[string]bool{"NONE": true, "ROW": true, "PAGE": true}
+
+// columnstoreDataCompressions are the supported values of the data_compression setting of columnstore indexes
+var columnstoreDataCompressions = map[string]bool{"COLUMNSTORE": true, "COLUMNSTORE_ARCHIVE": true}
+
+// isColumnstore reports whether the index type is a columnstore
+func isColumnstore(indexType string) bool {
+	return strings.HasSuffix(indexType, "COLUMNSTORE")
+}
+
+// indexSettings returns the settings of the index tags of idx's fields, gorm only keeps a few of them in schema.Index
+func (m Migrator) indexSettings(stmt *gorm.Statement, idx *schema.Index) map[string]string {
+	settings := map[string]string{}
+	for _, opt := range idx.Fields {
+		for key, setting := range m.indexFieldSettings(stmt, idx, opt) {
+			if _, ok := settings[key]; !ok {
+				settings[key] = setting
+			}
+		}
+	}
+	return settings
+}
+
+// indexFieldSettings returns the settings of the index tag of field opt declaring index idx, the tag is parsed with
+// schema.ParseTagSetting, so separators escaped with a backslash don't split settings, repeated index keys are all kept
+func (m Migrator) indexFieldSettings(stmt *gorm.Statement, idx *schema.Index, opt schema.IndexOption) map[string]string {
+	settings := map[string]string{}
+	for _, value := range splitTagSettings(opt.Tag.Get("gorm"), ";") {
+		for k, tag := range schema.ParseTagSetting(value, ";") {
+			if k != "INDEX" && k != "UNIQUEINDEX" {
+				continue
+			}
+
+			parts := splitTagSettings(tag, ",")
+			name, fieldSettings := parts[0], schema.ParseTagSetting(strings.Join(parts[1:], ","), ",")
+			if name == k {
+				// a key without value, e.g. index
+				name = ""
+			}
+			if name == "" {
+				subName := opt.Name
+				if composite := fieldSettings["COMPOSITE"]; composite != "" {
+					subName = composite
+				}
+				name = m.DB.NamingStrategy.IndexName(stmt.Schema.Table, subName)
+			}
+			if name != idx.Name {
+				continue
+			}
+
+			for key, setting := range fieldSettings {
+				if _, ok := settings[key]; !ok {
+					settings[key] = setting
+				}
+			}
+		}
+	}
+	return settings
+}
+
+// splitTagSettings splits the tag by sep like schema.ParseTagSetting, but keeps repeated keys and the escaping
+// backslashes, each part is parsed by schema.ParseTagSetting
+func splitTagSettings(tag, sep string) []string {
+	var parts []string
+	for _, part := range strings.Split(tag, sep) {
+		if n := len(parts); n > 0 && strings.HasSuffix(parts[n-1], "\\") {
+			parts[n-1] += sep + part
+		} else {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// indexOptionsOf parses and validates the settings of index idx
+func (m Migrator) indexOptionsOf(stmt *gorm.Statement, idx *schema.Index) (opts indexOptions, err error) {
+	settings := m.indexSettings(stmt, idx)
+
+	if indexType := strings.Join(strings.Fields(strings.ToUpper(idx.Type)), " "); indexType != "" {
+		var ok bool
+		if opts.Type, ok = indexTypes[indexType]; !ok {
+			return opts, fmt.Errorf("invalid type %s of index %s", idx.Type, idx.Name)
+		}
+	}
+	columnstore := isColumnstore(opts.Type)
+
+	for _, field := range idx.Fields {
+		sort := strings.ToUpper(strings.TrimSpace(field.Sort))
+		if sort != "" && (columnstore || (sort != "ASC" && sort != "DESC")) {
+			return opts, fmt.Errorf("invalid sort %s of column %s in index %s", field.Sort, field.DBName, idx.Name)
+		}
+	}
+
+	for _, name := range strings.Fields(strings.ReplaceAll(settings["INCLUDE"], ",", " ")) {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return opts, fmt.Errorf("unknown include column %s of index %s", name, idx.Name)
+		}
+		opts.Include = append(opts.Include, field.DBName)
+	}
+
+	if value, ok := settings["FILLFACTOR"]; ok {
+		if opts.FillFactor, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || opts.FillFactor < 1 || opts.FillFactor > 100 {
+			return opts, fmt.Errorf("invalid fillfactor %s of index %s, expected 1 to 100", value, idx.Name)
+		}
+	}
+
+	if value, ok := settings["MAXDOP"]; ok {
+		if opts.MaxDOP, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || opts.MaxDOP < 0 {
+			return opts, fmt.Errorf("invalid maxdop %s of index %s", value, idx.Name)
+		}
+	}
+
+	if value, ok := settings["DATA_COMPRESSION"]; ok {
+		opts.DataCompression = strings.ToUpper(strings.TrimSpace(value))
+		if (!columnstore && !dataCompressions[opts.DataCompression]) || (columnstore && !columnstoreDataCompressions[opts.DataCompression]) {
+			return opts, fmt.Errorf("invalid data_compression %s of index %s", value, idx.Name)
+		}
+	}
+
+	if value, ok := settings["COMPRESSION_DELAY"]; ok {
+		if opts.CompressionDelay, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || opts.CompressionDelay < 0 {
+			return opts, fmt.Errorf("invalid compression_delay %s of index %s", value, idx.Name)
+		}
+	}
+
+	opts.PadIndex = isSwitchedOn(settings, "PAD_INDEX")
+	opts.Online = isSwitchedOn(settings, "ONLINE")
+	opts.SortInTempDB = isSwitchedOn(settings, "SORT_IN_TEMPDB")
+
+	if columnstore {
+		switch {
+		case idx.Class != "":
+			return opts, fmt.Errorf("columnstore index %s can't be %s", idx.Name, idx.Class)
+		case len(opts.Include) > 0 || opts.FillFactor > 0 || opts.PadIndex:
+			return opts, fmt.Errorf("columnstore index %s doesn't support include, fillfactor and pad_index", idx.Name)
+		case opts.Type == "CLUSTERED COLUMNSTORE" && idx.Where != "":
+			return opts, fmt.Errorf("clustered columnstore index %s can't be filtered", idx.Name)
+		}
+	} else if opts.CompressionDelay > 0 {
+		return opts, fmt.Errorf("compression_delay of index %s requires a columnstore index", idx.Name)
+	}
+	return opts, nil
+}
+
+// isSwitchedOn reports whether the flag key is set in settings, either alone or with a value other than OFF or FALSE
+func isSwitchedOn(settings map[string]string, key string) bool {
+	value, ok := settings[key]
+	if !ok {
+		return false
+	}
+	value = strings.ToUpper(strings.TrimSpace(value))
+	return value != "OFF" && value != "FALSE" && value != "0"
+}
+
+// with renders the WITH clause of the index options, it is empty if all options are defaults
+func (opts indexOptions) with() string {
+	var options []string
+	if opts.PadIndex {
+		options = append(options, "PAD_INDEX = ON")
+	}
+	if opts.FillFactor > 0 {
+		options = append(options, fmt.Sprintf("FILLFACTOR = %d", opts.FillFactor))
+	}
+	if opts.SortInTempDB {
+		options = append(options, "SORT_IN_TEMPDB = ON")
+	}
+	if opts.Online {
+		options = append(options, "ONLINE = ON")
+	}
+	if opts.MaxDOP > 0 {
+		options = append(options, fmt.Sprintf("MAXDOP = %d", opts.MaxDOP))
+	}
+	if opts.DataCompression != "" {
+		options = append(options, "DATA_COMPRESSION = "+opts.DataCompression)
+	}
+	if opts.CompressionDelay > 0 {
+		options = append(options, fmt.Sprintf("COMPRESSION_DELAY = %d", opts.CompressionDelay))
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return "WITH (" + strings.Join(options, ", ") + ")"
+}
+
+// indexChanged reports whether the existing index differs from idx in its columns or persisted options,
+// build options like ONLINE, SORT_IN_TEMPDB and MAXDOP are not compared
+func indexChanged(idx *schema.Index, opts indexOptions, existing *IndexInfo) bool {
+	indexType := opts.Type
+	if indexType == "" {
+		indexType = "NONCLUSTERED"
+	}
+	columnstore := isColumnstore(indexType)
+
+	columns := make([]string, 0, len(idx.Fields))
+	var descending []string
+	for _, field := range idx.Fields {
+		if field.Expression != "" {
+			// expressions can't be compared with the index columns
+			return false
+		}
+		columns = append(columns, field.DBName)
+		if strings.EqualFold(strings.TrimSpace(field.Sort), "DESC") {
+			descending = append(descending, field.DBName)
+		}
+	}
+
+	fillFactor, existingFillFactor := normalizeFillFactor(opts.FillFactor), normalizeFillFactor(existing.FillFactor)
+	dataCompression := opts.DataCompression
+	if dataCompression == "" {
+		dataCompression = defaultDataCompression(indexType)
+	}
+	existingCompression := existing.DataCompression
+	if existingCompression == "" {
+		existingCompression = defaultDataCompression(existing.TypeValue)
+	}
+
+	if columnstore {
+		// the clustered columnstore index contains all columns, nonclustered columnstore columns are unordered
+		return (indexType == "NONCLUSTERED COLUMNSTORE" && !equalNames(columns, existing.ColumnList, false)) ||
+			!strings.EqualFold(indexType, existing.TypeValue) ||
+			normalizeFilter(idx.Where) != normalizeFilter(existing.Filter) ||
+			!strings.EqualFold(dataCompression, existingCompression) ||
+			opts.CompressionDelay != existing.CompressionDelay
+	}
+
+	return !equalNames(columns, existing.ColumnList, true) ||
+		!equalNames(descending, existing.DescendingColumns, false) ||
+		!equalNames(opts.Include, existing.IncludeColumns, false) ||
+		(idx.Class == "UNIQUE") != existing.UniqueValue.Bool ||
+		!strings.EqualFold(indexType, existing.TypeValue) ||
+		normalizeFilter(idx.Where) != normalizeFilter(existing.Filter) ||
+		fillFactor != existingFillFactor ||
+		!strings.EqualFold(dataCompression, existingCompression)
+}
+
+// normalizeFillFactor returns 0 for the fill factor 100, both fill the leaf pages completely
+func normalizeFillFactor(fillFactor int) int {
+	if fillFactor == 100 {
+		return 0
+	}
+	return fillFactor
+}
+
+// defaultDataCompression returns the data compression of indexes of the type created without data_compression setting
+func defaultDataCompression(indexType string) string {
+	if isColumnstore(indexType) {
+		return "COLUMNSTORE"
+	}
+	return "NONE"
+}
+
+// equalNames compares column names case insensitively, ignoring their order unless ordered
+func equalNames(names, others []string, ordered bool) bool {
+	if len(names) != len(others) {
+		return false
+	}
+	for i, name := range names {
+		if ordered {
+			if !strings.EqualFold(name, others[i]) {
+				return false
+			}
+			continue
+		}
+		found := false
+		for _, other := range others {
+			if strings.EqualFold(name, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeFilter strips the brackets, parentheses and whitespaces SQL Server adds to filter definitions,
+// e.g. ([age]>(18)) and age > 18 are both normalized to age>18. String literals are kept as they are,
+// only the N prefix SQL Server adds to them is stripped, e.g. ([status]=N'Active') is normalized to status='Active'.
+func normalizeFilter(filter string) string {
+	var (
+		builder strings.Builder
+		quoted  bool
+		runes   = []rune(filter)
+	)
+	for idx, r := range runes {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			builder.WriteRune(r)
+		case quoted:
+			builder.WriteRune(r)
+		case (r == 'N' || r == 'n') && idx+1 < len(runes) && runes[idx+1] == '\'' && (idx == 0 || !isIdentifierRune(runes[idx-1])):
+			// the N prefix of a unicode string literal
+		case strings.ContainsRune("[]()\" \t\n\r", r):
+		default:
+			builder.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return builder.String()
+}
+
+// isIdentifierRune reports whether r can be part of an unquoted identifier
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r == '@' || r == '#' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
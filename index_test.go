@@ -0,0 +1,80 @@
+package sqlserver
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+func TestIndexChanged_FillFactor(t *testing.T) {
+	idx := &schema.Index{Name: "idx_name", Fields: []schema.IndexOption{{Field: &schema.Field{DBName: "name"}}}}
+
+	tests := []struct {
+		name       string
+		fillFactor int
+		existing   int
+		want       bool
+	}{
+		{name: "default", fillFactor: 0, existing: 0, want: false},
+		{name: "full pages", fillFactor: 100, existing: 0, want: false},
+		{name: "full pages reported as 100", fillFactor: 0, existing: 100, want: false},
+		{name: "same fill factor", fillFactor: 80, existing: 80, want: false},
+		{name: "changed fill factor", fillFactor: 80, existing: 0, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &IndexInfo{TypeValue: "NONCLUSTERED", FillFactor: tt.existing, DataCompression: "NONE"}
+			existing.ColumnList = []string{"name"}
+			if got := indexChanged(idx, indexOptions{FillFactor: tt.fillFactor}, existing); got != tt.want {
+				t.Errorf("indexChanged() with fill factor %d and existing %d = %v, want %v", tt.fillFactor, tt.existing, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	tests := []struct {
+		filter, existing string
+		want             bool
+	}{
+		{filter: "age > 18", existing: "([age]>(18))", want: true},
+		{filter: "status = 'Active'", existing: "([status]='Active')", want: true},
+		{filter: "status = 'Active'", existing: "([status]=N'Active')", want: true},
+		{filter: "status IN (N'a b', 'c')", existing: "([status]=N'a b' OR [status]=N'c')", want: false},
+		{filter: "status = 'Active'", existing: "([status]='active')", want: false},
+		{filter: "note = 'a  b'", existing: "([note]='a b')", want: false},
+		{filter: "name = 'x'", existing: "([name]='x')", want: true},
+	}
+	for _, tt := range tests {
+		if got := normalizeFilter(tt.filter) == normalizeFilter(tt.existing); got != tt.want {
+			t.Errorf("normalizeFilter(%q) == normalizeFilter(%q) is %v, want %v", tt.filter, tt.existing, got, tt.want)
+		}
+	}
+}
+
+type testIndexSettings struct {
+	ID     uint
+	Name   string `gorm:"index;index:idx_name_status,include:note,where:status IN ('a'\\,'b')"`
+	Status string `gorm:"index:idx_name_status"`
+	Note   string
+}
+
+func TestIndexSettings(t *testing.T) {
+	s, err := schema.Parse(&testIndexSettings{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse schema, got error: %v", err)
+	}
+	m := Migrator{migrator.Migrator{Config: migrator.Config{DB: &gorm.DB{Config: &gorm.Config{NamingStrategy: schema.NamingStrategy{}}}}}}
+	stmt := &gorm.Statement{Schema: s}
+
+	settings := m.indexSettings(stmt, s.LookIndex("idx_name_status"))
+	if settings["INCLUDE"] != "note" || settings["WHERE"] != "status IN ('a','b')" {
+		t.Errorf("unexpected settings of idx_name_status %v", settings)
+	}
+	if settings := m.indexSettings(stmt, s.LookIndex("idx_test_index_settings_name")); len(settings) != 0 {
+		t.Errorf("unexpected settings of idx_test_index_settings_name %v", settings)
+	}
+}
@@ -1,8 +1,11 @@
 package sqlserver
 
 import (
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -18,30 +21,128 @@ SELECT
 	col.name AS column_name,
 	i.name AS index_name,
 	i.is_unique,
-	i.is_primary_key
+	i.is_primary_key,
+	i.is_unique_constraint,
+	i.type_desc,
+	i.filter_definition,
+	i.fill_factor,
+	i.compression_delay,
+	p.data_compression_desc AS data_compression,
+	ic.key_ordinal,
+	ic.is_included_column,
+	ic.is_descending_key
 FROM
 	sys.indexes i
 	LEFT JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
 	LEFT JOIN sys.all_columns col ON col.column_id = ic.column_id AND col.object_id = ic.object_id
+	LEFT JOIN sys.partitions p ON p.object_id = i.object_id AND p.index_id = i.index_id AND p.partition_number = 1
 WHERE 
 	i.name IS NOT NULL
-	AND i.is_unique_constraint = 0
 	AND i.object_id = OBJECT_ID(?)
+ORDER BY i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id
 `
 
 type Migrator struct {
 	migrator.Migrator
 }
 
+// GetTables returns the tables of the current database, tables outside of the default schema are qualified with their schema
 func (m Migrator) GetTables() (tableList []string, err error) {
-	return tableList, m.DB.Raw("SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE  TABLE_CATALOG = ?", m.CurrentDatabase()).Scan(&tableList).Error
+	return tableList, m.DB.Raw(
+		"SELECT CASE WHEN TABLE_SCHEMA = ? THEN TABLE_NAME ELSE TABLE_SCHEMA + '.' + TABLE_NAME END FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_CATALOG = ? AND TABLE_TYPE = ?",
+		m.DefaultSchema(), m.CurrentDatabase(), "BASE TABLE",
+	).Scan(&tableList).Error
+}
+
+// withDefaultSchema returns a migrator whose statements target value's table in Config.MigratorDefaultSchema,
+// if it is configured and the table has no explicit schema
+func (m Migrator) withDefaultSchema(value interface{}) Migrator {
+	defaultSchema := configOf(m.DB).MigratorDefaultSchema
+	if defaultSchema == "" {
+		return m
+	}
+
+	var table string
+	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		table = stmt.Table
+		return nil
+	})
+	if table == "" || strings.Contains(table, ".") {
+		return m
+	}
+
+	m.DB = m.DB.Table(defaultSchema + "." + table)
+	return m
+}
+
+// checkFields returns the error of the first field of the models with an invalid collation or identity seed,
+// they are checked before migrating any table
+func (m Migrator) checkFields(values ...interface{}) error {
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if stmt.Schema == nil {
+				return nil
+			}
+			for _, field := range stmt.Schema.Fields {
+				if err := checkField(field); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkField(field *schema.Field) error {
+	if _, err := collationOf(field); err != nil {
+		return err
+	}
+	_, _, err := identityOf(field)
+	return err
 }
 
 func (m Migrator) CreateTable(values ...interface{}) (err error) {
-	if err = m.Migrator.CreateTable(values...); err != nil {
+	values = m.ReorderModels(values, false)
+	if err = m.checkFields(values...); err != nil {
+		return err
+	}
+	foreignKeys, err := m.planForeignKeys(values)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		tx := m.withoutForeignKeys().withDefaultSchema(value)
+		if configOf(m.DB).AutoCreateSchema {
+			if err = tx.createMissingSchema(value); err != nil {
+				return
+			}
+		}
+		comment, hasComment := tx.tableCommentOf(value)
+		tx, _, _ = tx.withoutTableOptionsComment()
+		if err = tx.Migrator.CreateTable(value); err != nil {
+			return
+		}
+		if err = tx.createClusteredColumnstore(value); err != nil {
+			return
+		}
+		if hasComment {
+			target, err := tx.ExtendedPropertyTargetOf(value)
+			if err != nil {
+				return err
+			}
+			if err = tx.execExtendedProperty("sp_addextendedproperty", target, "MS_Description", comment); err != nil {
+				return err
+			}
+		}
+	}
+	if err = m.createForeignKeys(foreignKeys, false); err != nil {
 		return
 	}
-	for _, value := range m.ReorderModels(values, false) {
+	for _, value := range values {
 		if err = m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
 			if stmt.Schema == nil {
 				return
@@ -63,41 +164,75 @@ func (m Migrator) CreateTable(values ...interface{}) (err error) {
 	return
 }
 
+// ColumnstoreTable is implemented by models whose tables are created as clustered columnstore instead of rowstore,
+// their primary keys are created as nonclustered
+type ColumnstoreTable interface {
+	ClusteredColumnstore() bool
+}
+
+// createClusteredColumnstore creates the clustered columnstore index of a ColumnstoreTable model,
+// unless it declares a clustered columnstore index itself
+func (m Migrator) createClusteredColumnstore(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return nil
+		}
+		if table, ok := reflect.New(stmt.Schema.ModelType).Interface().(ColumnstoreTable); !ok || !table.ClusteredColumnstore() {
+			return nil
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if opts, err := m.indexOptionsOf(stmt, idx); err == nil && opts.Type == "CLUSTERED COLUMNSTORE" {
+				return nil
+			}
+		}
+
+		_, tableName := m.tableNameOf(stmt)
+		return m.withClusteredIndex(stmt, "cci_"+tableName, func(m Migrator) error {
+			return m.DB.Exec(
+				"CREATE CLUSTERED COLUMNSTORE INDEX ? ON ?",
+				clause.Column{Name: "cci_" + tableName}, clause.Table{Name: m.fullTableNameOf(stmt)},
+			).Error
+		})
+	})
+}
+
 func (m Migrator) setColumnComment(stmt *gorm.Statement, field *schema.Field, add bool) error {
-	schemaName := m.getTableSchemaName(stmt.Schema)
+	schemaName, tableName := m.tableNameOf(stmt)
 	commentExpr := gorm.Expr(strings.ReplaceAll(field.Comment, "'", "''"))
 	// add field comment
 	if add {
 		return m.DB.Exec(
 			"EXEC sp_addextendedproperty 'MS_Description', N'?', 'SCHEMA', ?, 'TABLE', ?, 'COLUMN', ?",
-			commentExpr, schemaName, stmt.Table, field.DBName,
+			commentExpr, schemaName, tableName, field.DBName,
 		).Error
 	}
 	// update field comment
 	return m.DB.Exec(
 		"EXEC sp_updateextendedproperty 'MS_Description', N'?', 'SCHEMA', ?, 'TABLE', ?, 'COLUMN', ?",
-		commentExpr, schemaName, stmt.Table, field.DBName,
+		commentExpr, schemaName, tableName, field.DBName,
 	).Error
 }
 
-func (m Migrator) getTableSchemaName(schema *schema.Schema) string {
-	// return the schema name if it is explicitly provided in the table name
-	// otherwise return default schema name
-	schemaName := getTableSchemaName(schema)
+// tableNameOf returns the schema and table name of the statement's table, tables without an explicit schema
+// belong to Config.MigratorDefaultSchema, or the default schema of the database user
+func (m Migrator) tableNameOf(stmt *gorm.Statement) (schemaName, tableName string) {
+	_, schemaName, tableName = splitFullQualifiedName(stmt.Table)
 	if schemaName == "" {
 		schemaName = m.DefaultSchema()
 	}
-	return schemaName
+	return
 }
 
-func getTableSchemaName(schema *schema.Schema) string {
-	// return the schema name if it is explicitly provided in the table name
-	// otherwise return a sql wildcard -> use any table_schema
-	if schema == nil || !strings.Contains(schema.Table, ".") {
-		return ""
+// fullTableNameOf returns the schema qualified name of the statement's table
+func (m Migrator) fullTableNameOf(stmt *gorm.Statement) string {
+	catalog, _, _ := splitFullQualifiedName(stmt.Table)
+	schemaName, tableName := m.tableNameOf(stmt)
+	if schemaName == "" {
+		return tableName
+	} else if catalog != "" {
+		return catalog + "." + schemaName + "." + tableName
 	}
-	_, schemaName, _ := splitFullQualifiedName(schema.Table)
-	return schemaName
+	return schemaName + "." + tableName
 }
 
 func splitFullQualifiedName(name string) (string, string, string) {
@@ -112,24 +247,13 @@ func splitFullQualifiedName(name string) (string, string, string) {
 	return "", "", ""
 }
 
-func getFullQualifiedTableName(stmt *gorm.Statement) string {
-	fullQualifiedTableName := stmt.Table
-	if schemaName := getTableSchemaName(stmt.Schema); schemaName != "" {
-		fullQualifiedTableName = schemaName + "." + fullQualifiedTableName
-	}
-	return fullQualifiedTableName
-}
-
 func (m Migrator) HasTable(value interface{}) bool {
 	var count int
 	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
-		schemaName := getTableSchemaName(stmt.Schema)
-		if schemaName == "" {
-			schemaName = "%"
-		}
+		schemaName, tableName := m.tableNameOf(stmt)
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ? AND TABLE_CATALOG = ? and TABLE_SCHEMA like ?  AND TABLE_TYPE = ?",
-			stmt.Table, m.CurrentDatabase(), schemaName, "BASE TABLE",
+			"SELECT count(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_NAME = ? AND TABLE_CATALOG = ? AND TABLE_SCHEMA = ? AND TABLE_TYPE = ?",
+			tableName, m.CurrentDatabase(), schemaName, "BASE TABLE",
 		).Row().Scan(&count)
 	})
 	return count > 0
@@ -144,17 +268,20 @@ func (m Migrator) DropTable(values ...interface{}) error {
 				Name   string
 				Parent string
 			}
-			var constraints []constraint
-			err := tx.Raw("SELECT name, OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(?)", getFullQualifiedTableName(stmt)).Scan(&constraints).Error
+			var (
+				constraints   []constraint
+				fullTableName = m.fullTableNameOf(stmt)
+			)
+			err := tx.Raw("SELECT name, OBJECT_SCHEMA_NAME(parent_object_id) + '.' + OBJECT_NAME(parent_object_id) as parent FROM sys.foreign_keys WHERE referenced_object_id = object_id(?)", fullTableName).Scan(&constraints).Error
 
 			for _, c := range constraints {
 				if err == nil {
-					err = tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?;", gorm.Expr(c.Parent), gorm.Expr(c.Name)).Error
+					err = tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?;", clause.Table{Name: c.Parent}, clause.Column{Name: c.Name}).Error
 				}
 			}
 
 			if err == nil {
-				err = tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: stmt.Table}).Error
+				err = tx.Exec("DROP TABLE IF EXISTS ?", clause.Table{Name: fullTableName}).Error
 			}
 
 			return err
@@ -168,11 +295,11 @@ func (m Migrator) DropTable(values ...interface{}) error {
 func (m Migrator) RenameTable(oldName, newName interface{}) error {
 	var oldTable, newTable string
 	if v, ok := oldName.(string); ok {
-		oldTable = v
+		oldTable = m.fullTableNameOf(&gorm.Statement{Table: v})
 	} else {
 		stmt := &gorm.Statement{DB: m.DB}
 		if err := stmt.Parse(oldName); err == nil {
-			oldTable = stmt.Table
+			oldTable = m.fullTableNameOf(stmt)
 		} else {
 			return err
 		}
@@ -189,14 +316,19 @@ func (m Migrator) RenameTable(oldName, newName interface{}) error {
 		}
 	}
 
+	// sp_rename only renames the table inside its schema, the new name mustn't be qualified
+	_, _, newTable = splitFullQualifiedName(newTable)
 	return m.DB.Exec(
 		"sp_rename @objname = ?, @newname = ?;",
-		clause.Table{Name: oldTable}, clause.Table{Name: newTable},
+		oldTable, newTable,
 	).Error
 }
 
 func (m Migrator) AddColumn(value interface{}, name string) error {
-	if err := m.Migrator.AddColumn(value, name); err != nil {
+	if err := m.checkFields(value); err != nil {
+		return err
+	}
+	if err := m.withDefaultSchema(value).Migrator.AddColumn(value, name); err != nil {
 		return err
 	}
 
@@ -215,10 +347,44 @@ func (m Migrator) AddColumn(value interface{}, name string) error {
 	})
 }
 
+// DropColumn drops the column after the objects depending on it, see ColumnDependencies
+func (m Migrator) DropColumn(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		column := name
+		if stmt.Schema != nil {
+			if field := stmt.Schema.LookUpField(name); field != nil {
+				column = field.DBName
+			}
+		}
+		dependencies, err := m.columnDependenciesOf(stmt, column)
+		if err != nil {
+			return err
+		}
+		dropColumn := func(m Migrator) error {
+			if err := m.dropColumnDependencies(dependencies); err != nil {
+				return err
+			}
+			return m.withDefaultSchema(value).Migrator.DropColumn(value, name)
+		}
+		// dry runs only log the statements, they don't begin a transaction on the database
+		if len(dependencies) == 0 || m.DB.DryRun {
+			return dropColumn(m)
+		}
+
+		// the dependencies are dropped with the column in a transaction, so that a failure doesn't lose them
+		return m.DB.Transaction(func(tx *gorm.DB) error {
+			m := m
+			m.DB = tx
+			return dropColumn(m)
+		})
+	})
+}
+
 func (m Migrator) HasColumn(value interface{}, field string) bool {
 	var count int64
 	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		currentDatabase := m.DB.Migrator().CurrentDatabase()
+		schemaName, tableName := m.tableNameOf(stmt)
 		name := field
 		if stmt.Schema != nil {
 			if field := stmt.Schema.LookUpField(field); field != nil {
@@ -227,35 +393,135 @@ func (m Migrator) HasColumn(value interface{}, field string) bool {
 		}
 
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_CATALOG = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?",
-			currentDatabase, stmt.Table, name,
+			"SELECT count(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_CATALOG = ? AND TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+			currentDatabase, schemaName, tableName, name,
 		).Row().Scan(&count)
 	})
 
 	return count > 0
 }
 
+// AlterColumn alters the type and nullability of the column, the objects depending on the column are dropped before,
+// the keys, foreign keys and the indexes and constraints declared by the model are created again afterwards,
+// see ColumnDependencies
 func (m Migrator) AlterColumn(value interface{}, field string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if stmt.Schema != nil {
 			if field := stmt.Schema.LookUpField(field); field != nil {
-				fieldType := clause.Expr{SQL: m.DataTypeOf(field)}
-				if field.NotNull {
-					fieldType.SQL += " NOT NULL"
-				} else {
-					fieldType.SQL += " NULL"
+				if err := checkField(field); err != nil {
+					return err
+				}
+				dependencies, err := m.columnDependenciesOf(stmt, field.DBName)
+				if err != nil {
+					return err
+				}
+				// dry runs only log the statements, they don't begin a transaction on the database
+				if len(dependencies) == 0 || m.DB.DryRun {
+					return m.alterColumn(value, stmt, field, dependencies)
 				}
 
-				return m.DB.Exec(
-					"ALTER TABLE ? ALTER COLUMN ? ?",
-					clause.Table{Name: getFullQualifiedTableName(stmt)}, clause.Column{Name: field.DBName}, fieldType,
-				).Error
+				// the dependencies are dropped and recreated in a transaction, so that a failure doesn't lose them
+				return m.DB.Transaction(func(tx *gorm.DB) error {
+					m := m
+					m.DB = tx
+					return m.alterColumn(value, stmt, field, dependencies)
+				})
 			}
 		}
 		return fmt.Errorf("failed to look up field with name: %s", field)
 	})
 }
 
+func (m Migrator) alterColumn(value interface{}, stmt *gorm.Statement, field *schema.Field, dependencies []ColumnDependency) error {
+	// the identity of a column can't be altered, primary keys stay not null
+	fieldType := clause.Expr{SQL: withoutIdentity(m.DataTypeOf(field))}
+	if field.NotNull || field.PrimaryKey {
+		fieldType.SQL += " NOT NULL"
+	} else {
+		fieldType.SQL += " NULL"
+	}
+
+	if err := m.dropColumnDependencies(dependencies); err != nil {
+		return err
+	}
+
+	table := clause.Table{Name: m.fullTableNameOf(stmt)}
+	if err := m.DB.Exec(
+		"ALTER TABLE ? ALTER COLUMN ? ?", table, clause.Column{Name: field.DBName}, fieldType,
+	).Error; err != nil {
+		return err
+	}
+	if err := m.checkReferencingColumns(table.Name, field.DBName, dependencies); err != nil {
+		return err
+	}
+
+	if defaultValue, ok := m.defaultValueOf(field); ok && !strings.EqualFold(defaultValue, "NULL") {
+		_, tableName := m.tableNameOf(stmt)
+		if err := m.DB.Exec(
+			"ALTER TABLE ? ADD CONSTRAINT ? DEFAULT ? FOR ?",
+			table, clause.Column{Name: defaultConstraintName(tableName, field.DBName)},
+			clause.Expr{SQL: defaultValue}, clause.Column{Name: field.DBName},
+		).Error; err != nil {
+			return err
+		}
+	}
+	return m.recreateColumnDependencies(value, dependencies)
+}
+
+// FullDataTypeOf returns the column definition of field, its default value is created as named constraint DF_table_column
+func (m Migrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	if isComputedField(field) {
+		expr.SQL = computedColumnDefinition(field)
+		return
+	}
+
+	expr.SQL = m.DataTypeOf(field)
+	if field.NotNull {
+		expr.SQL += " NOT NULL"
+	}
+
+	if defaultValue, ok := m.defaultValueOf(field); ok {
+		table := m.DB.Statement.Table
+		if table == "" && field.Schema != nil {
+			table = field.Schema.Table
+		}
+		if _, _, table = splitFullQualifiedName(table); table != "" {
+			var name strings.Builder
+			m.Dialector.QuoteTo(&name, defaultConstraintName(table, field.DBName))
+			expr.SQL += " CONSTRAINT " + name.String()
+		}
+		expr.SQL += " DEFAULT " + defaultValue
+	}
+	return
+}
+
+// defaultValueOf returns the SQL of the default value of field, ok is false if it has none
+func (m Migrator) defaultValueOf(field *schema.Field) (defaultValue string, ok bool) {
+	if !field.HasDefaultValue {
+		return "", false
+	}
+	if field.DefaultValueInterface != nil {
+		defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValueInterface}}
+		m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValueInterface)
+		return m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValueInterface), true
+	}
+	if field.DefaultValue == "" || field.DefaultValue == "(-)" {
+		return "", false
+	}
+	return field.DefaultValue, true
+}
+
+// defaultConstraintName returns the deterministic name DF_table_column of the default constraint of a column,
+// names exceeding the max identifier length are shortened with a hash
+func defaultConstraintName(table, column string) string {
+	name := "DF_" + table + "_" + column
+	if len(name) <= 128 {
+		return name
+	}
+	h := sha1.Sum([]byte(name))
+	return name[:119] + "_" + hex.EncodeToString(h[:])[:8]
+}
+
 func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if stmt.Schema != nil {
@@ -269,7 +535,7 @@ func (m Migrator) RenameColumn(value interface{}, oldName, newName string) error
 
 		return m.DB.Exec(
 			"sp_rename @objname = ?, @newname = ?, @objtype = 'COLUMN';",
-			fmt.Sprintf("%s.%s", stmt.Table, oldName), clause.Column{Name: newName},
+			fmt.Sprintf("%s.%s", m.fullTableNameOf(stmt), oldName), clause.Column{Name: newName},
 		).Error
 	})
 }
@@ -279,15 +545,49 @@ func (m Migrator) GetColumnComment(stmt *gorm.Statement, fieldDBName string) (co
 	if m.DB.DryRun {
 		queryTx.DryRun = false
 	}
+	schemaName, tableName := m.tableNameOf(stmt)
 	queryTx.Raw("SELECT value FROM [?].sys.fn_listextendedproperty('MS_Description', 'SCHEMA', ?, 'TABLE', ?, 'COLUMN', ?)",
-		gorm.Expr(m.CurrentDatabase()), m.getTableSchemaName(stmt.Schema), stmt.Table, fieldDBName).Scan(&comment)
+		gorm.Expr(m.CurrentDatabase()), schemaName, tableName, fieldDBName).Scan(&comment)
 	return
 }
 
+// MigrateColumn migrates the column with gorm's MigrateColumn, which compares the data type and default value of the
+// field literally, so the equivalent definitions SQL Server reports are normalized first. Changed data types are
+// altered directly, gorm misses some changes, e.g. of primary keys or from datetimeoffset(3) to datetimeoffset.
 func (m Migrator) MigrateColumn(value interface{}, field *schema.Field, columnType gorm.ColumnType) error {
-	if err := m.Migrator.MigrateColumn(value, field, columnType); err != nil {
+	if field.IgnoreMigration {
+		return nil
+	}
+
+	columnInfo, err := m.columnInfoOf(value, columnType)
+	if err != nil {
 		return err
 	}
+	if identityChanged(field, columnInfo) {
+		seed, increment, _ := identityOf(field)
+		m.DB.Logger.Warn(m.DB.Statement.Context, "column %s is IDENTITY(%d,%d) instead of IDENTITY(%d,%d), the identity of a column can't be altered",
+			field.DBName, columnInfo.IdentitySeed.Int64, columnInfo.IdentityIncrement.Int64, seed, increment)
+	}
+
+	// computed columns can't be altered, neither into nor from ordinary columns
+	if isComputedField(field) || columnInfo.Computed {
+		if err := m.Migrator.MigrateColumnUnique(value, field, columnType); err != nil {
+			return err
+		}
+	} else if m.dataTypeChanged(field, columnType) || collationChanged(field, columnInfo) {
+		// altering the column applies its nullability and default value as well
+		if err := m.DB.Migrator().AlterColumn(value, field.DBName); err != nil {
+			return err
+		}
+		if err := m.Migrator.MigrateColumnUnique(value, field, columnType); err != nil {
+			return err
+		}
+	} else {
+		migrator := m.withDefaultSchema(value)
+		if err := migrator.Migrator.MigrateColumn(value, field, migrator.normalizeColumnType(field, columnType)); err != nil {
+			return err
+		}
+	}
 
 	return m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
 		comment := m.GetColumnComment(stmt, field.DBName)
@@ -308,7 +608,8 @@ var defaultValueTrimRegexp = regexp.MustCompile("^\\('?([^']*)'?\\)$")
 func (m Migrator) ColumnTypes(value interface{}) ([]gorm.ColumnType, error) {
 	columnTypes := make([]gorm.ColumnType, 0)
 	execErr := m.RunWithValue(value, func(stmt *gorm.Statement) (err error) {
-		rows, err := m.DB.Session(&gorm.Session{}).Table(getFullQualifiedTableName(stmt)).Limit(1).Rows()
+		schemaName, tableName := m.tableNameOf(stmt)
+		rows, err := m.DB.Session(&gorm.Session{}).Table(m.fullTableNameOf(stmt)).Limit(1).Rows()
 		if err != nil {
 			return err
 		}
@@ -317,21 +618,14 @@ func (m Migrator) ColumnTypes(value interface{}) ([]gorm.ColumnType, error) {
 		_ = rows.Close()
 
 		{
-			_, schemaName, tableName := splitFullQualifiedName(stmt.Table)
-
 			query := strings.TrimSpace(`
-SELECT COLUMN_NAME, DATA_TYPE, COLUMN_DEFAULT, c.IS_NULLABLE, CHARACTER_MAXIMUM_LENGTH, NUMERIC_PRECISION, NUMERIC_PRECISION_RADIX, NUMERIC_SCALE, DATETIME_PRECISION, AUTO_INCREMENT = c2.is_identity
+SELECT COLUMN_NAME, DATA_TYPE, dc.definition AS COLUMN_DEFAULT, c.IS_NULLABLE, CHARACTER_MAXIMUM_LENGTH, NUMERIC_PRECISION, NUMERIC_PRECISION_RADIX, NUMERIC_SCALE, DATETIME_PRECISION, AUTO_INCREMENT = c2.is_identity
 FROM INFORMATION_SCHEMA.COLUMNS c
-LEFT JOIN sys.tables t ON c.TABLE_NAME = t.[name]
-LEFT JOIN sys.columns c2 ON t.object_id = c2.object_id AND c2.[name] = c.COLUMN_NAME
-WHERE TABLE_CATALOG = ? AND TABLE_NAME = ?`)
+LEFT JOIN sys.columns c2 ON c2.object_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME)) AND c2.[name] = c.COLUMN_NAME
+LEFT JOIN sys.default_constraints dc ON dc.parent_object_id = c2.object_id AND dc.parent_column_id = c2.column_id
+WHERE TABLE_CATALOG = ? AND TABLE_NAME = ? AND TABLE_SCHEMA = ?`)
 
-			queryParameters := []interface{}{m.CurrentDatabase(), tableName}
-
-			if schemaName != "" {
-				query += " AND TABLE_SCHEMA = ?"
-				queryParameters = append(queryParameters, schemaName)
-			}
+			queryParameters := []interface{}{m.CurrentDatabase(), tableName, schemaName}
 
 			var (
 				columnTypeSQL   = query
@@ -361,6 +655,7 @@ WHERE TABLE_CATALOG = ? AND TABLE_NAME = ?`)
 					return scanErr
 				}
 
+				column.DataTypeValue = column.ColumnTypeValue
 				if nullableValue.Valid {
 					column.NullableValue = sql.NullBool{Bool: strings.EqualFold(nullableValue.String, "YES"), Valid: true}
 				}
@@ -395,17 +690,9 @@ WHERE TABLE_CATALOG = ? AND TABLE_NAME = ?`)
 		}
 
 		{
-			_, schemaName, tableName := splitFullQualifiedName(stmt.Table)
-
-			query := "SELECT t.CONSTRAINT_NAME FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t JOIN INFORMATION_SCHEMA.CONSTRAINT_COLUMN_USAGE c ON c.CONSTRAINT_NAME=t.CONSTRAINT_NAME WHERE t.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE') AND c.TABLE_CATALOG = ? AND c.TABLE_NAME = ? AND CONSTRAINT_TYPE = ?"
+			query := "SELECT t.CONSTRAINT_NAME FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t JOIN INFORMATION_SCHEMA.CONSTRAINT_COLUMN_USAGE c ON c.CONSTRAINT_SCHEMA=t.CONSTRAINT_SCHEMA AND c.CONSTRAINT_NAME=t.CONSTRAINT_NAME WHERE t.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE') AND c.TABLE_CATALOG = ? AND c.TABLE_NAME = ? AND c.TABLE_SCHEMA = ? AND CONSTRAINT_TYPE = ?"
 
-			queryParameters := []interface{}{m.CurrentDatabase(), tableName}
-
-			if schemaName != "" {
-				query += " AND c.TABLE_SCHEMA = ?"
-				queryParameters = append(queryParameters, schemaName)
-			}
-			queryParameters = append(queryParameters, "UNIQUE")
+			queryParameters := []interface{}{m.CurrentDatabase(), tableName, schemaName, "UNIQUE"}
 			columnTypeRows, err := m.DB.Raw(query, queryParameters...).Rows()
 			if err != nil {
 				return err
@@ -418,14 +705,9 @@ WHERE TABLE_CATALOG = ? AND TABLE_NAME = ?`)
 			}
 			_ = columnTypeRows.Close()
 
-			query = "SELECT c.COLUMN_NAME, t.CONSTRAINT_NAME, t.CONSTRAINT_TYPE FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t JOIN INFORMATION_SCHEMA.CONSTRAINT_COLUMN_USAGE c ON c.CONSTRAINT_NAME=t.CONSTRAINT_NAME WHERE t.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE') AND c.TABLE_CATALOG = ? AND c.TABLE_NAME = ?"
+			query = "SELECT c.COLUMN_NAME, t.CONSTRAINT_NAME, t.CONSTRAINT_TYPE FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS t JOIN INFORMATION_SCHEMA.CONSTRAINT_COLUMN_USAGE c ON c.CONSTRAINT_SCHEMA=t.CONSTRAINT_SCHEMA AND c.CONSTRAINT_NAME=t.CONSTRAINT_NAME WHERE t.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE') AND c.TABLE_CATALOG = ? AND c.TABLE_NAME = ? AND c.TABLE_SCHEMA = ?"
 
-			queryParameters = []interface{}{m.CurrentDatabase(), tableName}
-
-			if schemaName != "" {
-				query += " AND c.TABLE_SCHEMA = ?"
-				queryParameters = append(queryParameters, schemaName)
-			}
+			queryParameters = []interface{}{m.CurrentDatabase(), tableName, schemaName}
 
 			columnTypeRows, err = m.DB.Raw(query, queryParameters...).Rows()
 			if err != nil {
@@ -484,6 +766,8 @@ func (m Migrator) CreateView(name string, option gorm.ViewOption) error {
 	return m.DB.Exec(m.Explain(sql.String(), m.DB.Statement.Vars...)).Error
 }
 
+// CreateIndex creates the index with the options of its tag, a clustered primary key conflicting with a clustered
+// index is recreated as nonclustered, see withClusteredIndex
 func (m Migrator) CreateIndex(value interface{}, name string) error {
 	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		var idx *schema.Index
@@ -493,29 +777,222 @@ func (m Migrator) CreateIndex(value interface{}, name string) error {
 		if idx == nil {
 			return fmt.Errorf("failed to create index with name %s", name)
 		}
+		if isFullTextIndex(idx) {
+			// the FULLTEXT indexes of a model are created as one full-text index
+			if m.HasFullTextIndex(value) {
+				return nil
+			}
+			return m.CreateFullTextIndex(value)
+		}
 
-		opts := m.BuildIndexOptions(idx.Fields, stmt)
-		values := []interface{}{clause.Column{Name: idx.Name}, m.CurrentTable(stmt), opts}
+		indexOpts, err := m.indexOptionsOf(stmt, idx)
+		if err != nil {
+			return err
+		}
+
+		values := []interface{}{clause.Column{Name: idx.Name}, clause.Table{Name: m.fullTableNameOf(stmt)}}
 
 		createIndexSQL := "CREATE "
 		if idx.Class != "" {
 			createIndexSQL += idx.Class + " "
 		}
-		createIndexSQL += "INDEX ? ON ??"
+		if indexOpts.Type != "" {
+			createIndexSQL += indexOpts.Type + " "
+		}
+		createIndexSQL += "INDEX ? ON ?"
+
+		// the clustered columnstore index contains all columns of the table
+		if indexOpts.Type != "CLUSTERED COLUMNSTORE" {
+			createIndexSQL += "?"
+			values = append(values, m.BuildIndexOptions(idx.Fields, stmt))
+		}
+
+		if len(indexOpts.Include) > 0 {
+			createIndexSQL += " INCLUDE ?"
+			values = append(values, columnsOf(indexOpts.Include))
+		}
 
 		if idx.Where != "" {
 			createIndexSQL += " WHERE " + idx.Where
 		}
 
+		if with := indexOpts.with(); with != "" {
+			createIndexSQL += " " + with
+		}
+
 		if idx.Option != "" {
 			createIndexSQL += " " + idx.Option
 		}
 
+		if strings.HasPrefix(indexOpts.Type, "CLUSTERED") {
+			return m.withClusteredIndex(stmt, idx.Name, func(m Migrator) error {
+				return m.DB.Exec(createIndexSQL, values...).Error
+			})
+		}
 		return m.DB.Exec(createIndexSQL, values...).Error
 	})
 }
 
+// AutoMigrate migrates the tables like the gorm migrator, but creates the foreign keys after all tables, recreates
+// the indexes whose columns or options differ from the index tags of the models and updates the changed table comments
+func (m Migrator) AutoMigrate(values ...interface{}) error {
+	if err := m.checkFields(values...); err != nil {
+		return err
+	}
+	foreignKeys, err := m.planForeignKeys(m.ReorderModels(values, true))
+	if err != nil {
+		return err
+	}
+	if err := m.withoutForeignKeys().Migrator.AutoMigrate(values...); err != nil {
+		return err
+	}
+	if err := m.createForeignKeys(foreignKeys, true); err != nil {
+		return err
+	}
+
+	for _, value := range m.ReorderModels(values, true) {
+		if err := m.migrateIndexes(value); err != nil {
+			return err
+		}
+		if err := m.migrateTableComment(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateIndexes drops and recreates the existing indexes of value that differ from the model
+func (m Migrator) migrateIndexes(value interface{}) error {
+	queryTx, execTx := m.GetQueryAndExecTx()
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return nil
+		}
+		parsedIndexes := stmt.Schema.ParseIndexes()
+		if len(parsedIndexes) == 0 {
+			return nil
+		}
+
+		indexes, err := queryTx.Migrator().GetIndexes(value)
+		if err != nil {
+			return err
+		}
+		existingIndexes := make(map[string]*IndexInfo, len(indexes))
+		for _, index := range indexes {
+			if info, ok := index.(*IndexInfo); ok {
+				existingIndexes[strings.ToLower(info.Name())] = info
+			}
+		}
+
+		for _, idx := range parsedIndexes {
+			existing, ok := existingIndexes[strings.ToLower(idx.Name)]
+			if !ok || existing.UniqueConstraint {
+				continue
+			}
+			if pk, _ := existing.PrimaryKey(); pk {
+				continue
+			}
+
+			opts, err := m.indexOptionsOf(stmt, idx)
+			if err != nil {
+				return err
+			}
+			if !indexChanged(idx, opts, existing) {
+				continue
+			}
+
+			if err := execTx.Migrator().DropIndex(value, idx.Name); err != nil {
+				return err
+			}
+			if err := execTx.Migrator().CreateIndex(value, idx.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withClusteredIndex creates the clustered index with create, a table has only one clustered index, so a clustered
+// primary key conflicts with it. The primary key is recreated as nonclustered with the foreign keys referencing it
+// before, in a transaction with create, so that a failure doesn't lose them.
+func (m Migrator) withClusteredIndex(stmt *gorm.Statement, name string, create func(m Migrator) error) error {
+	primaryKey, err := m.clusteredPrimaryKeyOf(stmt)
+	if err != nil {
+		return err
+	} else if primaryKey == nil {
+		return create(m)
+	}
+
+	m.DB.Logger.Warn(m.DB.Statement.Context, "primary key %s of %s is recreated as nonclustered for the clustered index %s", primaryKey.Name, primaryKey.Table, name)
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		m := m
+		m.DB = tx
+
+		foreignKeys, err := m.queryForeignKeys(
+			"fk.referenced_object_id = OBJECT_ID(?) AND fk.key_index_id = INDEXPROPERTY(fk.referenced_object_id, ?, 'IndexID')",
+			primaryKey.Table, primaryKey.Name,
+		)
+		if err != nil {
+			return err
+		}
+		for idx := range foreignKeys {
+			foreignKeys[idx].Recreate = true
+		}
+
+		dependencies := append(foreignKeys, *primaryKey)
+		if err := m.dropColumnDependencies(dependencies); err != nil {
+			return err
+		}
+		dependencies[len(dependencies)-1].nonclustered = true
+		if err := m.recreateColumnDependencies(nil, dependencies); err != nil {
+			return err
+		}
+		return create(m)
+	})
+}
+
+// clusteredPrimaryKeyOf returns the primary key of the table if it is the clustered index, or nil
+func (m Migrator) clusteredPrimaryKeyOf(stmt *gorm.Statement) (*ColumnDependency, error) {
+	if m.DB.DryRun {
+		return nil, nil
+	}
+
+	table := m.fullTableNameOf(stmt)
+	var names []string
+	if err := m.DB.Raw(
+		"SELECT name FROM sys.indexes WHERE object_id = OBJECT_ID(?) AND is_primary_key = 1 AND type_desc = 'CLUSTERED'",
+		table,
+	).Scan(&names).Error; err != nil || len(names) == 0 {
+		return nil, err
+	}
+
+	primaryKey := &ColumnDependency{Type: DependencyPrimaryKey, Name: names[0], Table: table, Recreate: true}
+	columns, err := m.indexColumnsOf(table, primaryKey.Name)
+	primaryKey.columns = columns
+	return primaryKey, err
+}
+
+func (m Migrator) DropIndex(value interface{}, name string) error {
+	if m.isFullTextIndexName(value, name) {
+		return m.DropFullTextIndex(value)
+	}
+	return m.withDefaultSchema(value).Migrator.DropIndex(value, name)
+}
+
+// isFullTextIndexName reports whether name is a FULLTEXT index of the model
+func (m Migrator) isFullTextIndexName(value interface{}, name string) (fullText bool) {
+	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		fullText = stmt.Schema != nil && isFullTextIndex(stmt.Schema.LookIndex(name))
+		return nil
+	})
+	return
+}
+
 func (m Migrator) HasIndex(value interface{}, name string) bool {
+	if m.isFullTextIndexName(value, name) {
+		return m.HasFullTextIndex(value)
+	}
+
 	var count int
 	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		if stmt.Schema != nil {
@@ -526,7 +1003,7 @@ func (m Migrator) HasIndex(value interface{}, name string) bool {
 
 		return m.DB.Raw(
 			"SELECT count(*) FROM sys.indexes WHERE name=? AND object_id=OBJECT_ID(?)",
-			name, getFullQualifiedTableName(stmt),
+			name, m.fullTableNameOf(stmt),
 		).Row().Scan(&count)
 	})
 	return count > 0
@@ -537,48 +1014,122 @@ func (m Migrator) RenameIndex(value interface{}, oldName, newName string) error
 
 		return m.DB.Exec(
 			"sp_rename @objname = ?, @newname = ?, @objtype = 'INDEX';",
-			fmt.Sprintf("%s.%s", stmt.Table, oldName), clause.Column{Name: newName},
+			fmt.Sprintf("%s.%s", m.fullTableNameOf(stmt), oldName), clause.Column{Name: newName},
 		).Error
 	})
 }
 
 type Index struct {
-	ColumnName   string       `gorm:"column:column_name"`
-	IndexName    string       `gorm:"column:index_name"`
-	IsUnique     sql.NullBool `gorm:"column:is_unique"`
-	IsPrimaryKey sql.NullBool `gorm:"column:is_primary_key"`
+	ColumnName         string         `gorm:"column:column_name"`
+	IndexName          string         `gorm:"column:index_name"`
+	IsUnique           sql.NullBool   `gorm:"column:is_unique"`
+	IsPrimaryKey       sql.NullBool   `gorm:"column:is_primary_key"`
+	IsUniqueConstraint sql.NullBool   `gorm:"column:is_unique_constraint"`
+	TypeDesc           string         `gorm:"column:type_desc"`
+	FilterDefinition   sql.NullString `gorm:"column:filter_definition"`
+	FillFactor         sql.NullInt64  `gorm:"column:fill_factor"`
+	CompressionDelay   sql.NullInt64  `gorm:"column:compression_delay"`
+	DataCompression    sql.NullString `gorm:"column:data_compression"`
+	KeyOrdinal         sql.NullInt64  `gorm:"column:key_ordinal"`
+	IsIncludedColumn   sql.NullBool   `gorm:"column:is_included_column"`
+	IsDescendingKey    sql.NullBool   `gorm:"column:is_descending_key"`
+}
+
+// IndexInfo is the gorm.Index returned by GetIndexes, it carries the SQL Server specific properties of the index,
+// the options differing from the defaults are rendered as WITH clause by Option
+type IndexInfo struct {
+	migrator.Index
+	// TypeValue is the index type, e.g. CLUSTERED, NONCLUSTERED or CLUSTERED COLUMNSTORE
+	TypeValue         string
+	IncludeColumns    []string
+	DescendingColumns []string
+	Filter            string
+	FillFactor        int
+	DataCompression   string
+	// CompressionDelay is the compression delay of columnstore indexes in minutes
+	CompressionDelay int
+	UniqueConstraint bool
+}
+
+// Type returns the index type, e.g. CLUSTERED, NONCLUSTERED or CLUSTERED COLUMNSTORE
+func (idx IndexInfo) Type() string {
+	return idx.TypeValue
 }
 
 func (m Migrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
 	indexes := make([]gorm.Index, 0)
 	err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		result := make([]*Index, 0)
-		if err := m.DB.Raw(indexSQL, stmt.Table).Scan(&result).Error; err != nil {
+		if err := m.DB.Raw(indexSQL, m.fullTableNameOf(stmt)).Scan(&result).Error; err != nil {
 			return err
 		}
-		indexMap := make(map[string]*migrator.Index)
+		indexMap := make(map[string]*IndexInfo)
 		for _, r := range result {
 			idx, ok := indexMap[r.IndexName]
 			if !ok {
-				idx = &migrator.Index{
-					TableName:       stmt.Table,
-					NameValue:       r.IndexName,
-					ColumnList:      nil,
-					PrimaryKeyValue: r.IsPrimaryKey,
-					UniqueValue:     r.IsUnique,
+				idx = &IndexInfo{
+					Index: migrator.Index{
+						TableName:       stmt.Table,
+						NameValue:       r.IndexName,
+						ColumnList:      nil,
+						PrimaryKeyValue: r.IsPrimaryKey,
+						UniqueValue:     r.IsUnique,
+					},
+					TypeValue:        r.TypeDesc,
+					Filter:           r.FilterDefinition.String,
+					FillFactor:       int(r.FillFactor.Int64),
+					DataCompression:  r.DataCompression.String,
+					CompressionDelay: int(r.CompressionDelay.Int64),
+					UniqueConstraint: r.IsUniqueConstraint.Bool,
+				}
+				idx.OptionValue = idx.options()
+				indexMap[r.IndexName] = idx
+				indexes = append(indexes, idx)
+			}
+			if r.ColumnName == "" {
+				continue
+			}
+			// the columns of columnstore indexes are reported as included columns
+			if r.IsIncludedColumn.Bool && !isColumnstore(idx.TypeValue) {
+				idx.IncludeColumns = append(idx.IncludeColumns, r.ColumnName)
+			} else {
+				idx.ColumnList = append(idx.ColumnList, r.ColumnName)
+				if r.IsDescendingKey.Bool {
+					idx.DescendingColumns = append(idx.DescendingColumns, r.ColumnName)
 				}
 			}
-			idx.ColumnList = append(idx.ColumnList, r.ColumnName)
-			indexMap[r.IndexName] = idx
-		}
-		for _, idx := range indexMap {
-			indexes = append(indexes, idx)
 		}
 		return nil
 	})
 	return indexes, err
 }
 
+// options renders the options of the index differing from the defaults
+func (idx IndexInfo) options() string {
+	var options []string
+	if idx.FillFactor > 0 && idx.FillFactor < 100 {
+		options = append(options, fmt.Sprintf("FILLFACTOR = %d", idx.FillFactor))
+	}
+	if idx.DataCompression != "" && idx.DataCompression != defaultDataCompression(idx.TypeValue) {
+		options = append(options, "DATA_COMPRESSION = "+idx.DataCompression)
+	}
+	if idx.CompressionDelay > 0 {
+		options = append(options, fmt.Sprintf("COMPRESSION_DELAY = %d", idx.CompressionDelay))
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return "WITH (" + strings.Join(options, ", ") + ")"
+}
+
+func (m Migrator) CreateConstraint(value interface{}, name string) error {
+	return m.withDefaultSchema(value).Migrator.CreateConstraint(value, name)
+}
+
+func (m Migrator) DropConstraint(value interface{}, name string) error {
+	return m.withDefaultSchema(value).Migrator.DropConstraint(value, name)
+}
+
 func (m Migrator) HasConstraint(value interface{}, name string) bool {
 	var count int64
 	_ = m.RunWithValue(value, func(stmt *gorm.Statement) error {
@@ -587,13 +1138,11 @@ func (m Migrator) HasConstraint(value interface{}, name string) bool {
 			name = constraint.GetName()
 		}
 
-		tableCatalog, tableSchema, tableName := splitFullQualifiedName(table)
+		tableCatalog, _, _ := splitFullQualifiedName(table)
 		if tableCatalog == "" {
 			tableCatalog = m.CurrentDatabase()
 		}
-		if tableSchema == "" {
-			tableSchema = "%"
-		}
+		tableSchema, tableName := m.tableNameOf(&gorm.Statement{Table: table})
 
 		return m.DB.Raw(
 			`SELECT count(*) FROM (
@@ -631,7 +1180,106 @@ func (m Migrator) CurrentDatabase() (name string) {
 	return
 }
 
+// DefaultSchema returns Config.MigratorDefaultSchema if configured, otherwise the default schema of the database user
 func (m Migrator) DefaultSchema() (name string) {
+	if name = configOf(m.DB).MigratorDefaultSchema; name != "" {
+		return
+	}
 	_ = m.DB.Raw("SELECT SCHEMA_NAME() AS [Default Schema]").Row().Scan(&name)
 	return
 }
+
+// GetSchemas returns the user schemas of the current database
+func (m Migrator) GetSchemas() (schemaList []string, err error) {
+	return schemaList, m.DB.Raw(
+		"SELECT name FROM sys.schemas WHERE schema_id < 16384 AND name NOT IN ('sys', 'INFORMATION_SCHEMA', 'guest') ORDER BY name",
+	).Scan(&schemaList).Error
+}
+
+func (m Migrator) HasSchema(name string) bool {
+	var count int
+	_ = m.DB.Raw("SELECT count(*) FROM sys.schemas WHERE name = ?", name).Row().Scan(&count)
+	return count > 0
+}
+
+func (m Migrator) CreateSchema(name string) error {
+	return m.DB.Exec("CREATE SCHEMA ?", clause.Column{Name: name}).Error
+}
+
+// schemaObjectTypes lists the objects DropSchema drops with cascade, in drop order
+var schemaObjectTypes = []struct {
+	types []string
+	drop  string
+}{
+	{types: []string{"SN"}, drop: "DROP SYNONYM ?"},
+	{types: []string{"V"}, drop: "DROP VIEW ?"},
+	{types: []string{"P"}, drop: "DROP PROCEDURE ?"},
+	{types: []string{"FN", "IF", "TF"}, drop: "DROP FUNCTION ?"},
+	{types: []string{"U"}, drop: "DROP TABLE ?"},
+	{types: []string{"SO"}, drop: "DROP SEQUENCE ?"},
+}
+
+// DropSchema drops the schema, with cascade the views, procedures, functions, tables, sequences and types it contains
+// and the foreign keys referencing its tables are dropped first
+func (m Migrator) DropSchema(name string, cascade bool) error {
+	if !cascade {
+		return m.DB.Exec("DROP SCHEMA ?", clause.Column{Name: name}).Error
+	}
+
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		type constraint struct {
+			Name   string
+			Parent string
+		}
+		var constraints []constraint
+		if err := tx.Raw(
+			"SELECT fk.name, OBJECT_SCHEMA_NAME(fk.parent_object_id) + '.' + OBJECT_NAME(fk.parent_object_id) as parent FROM sys.foreign_keys fk "+
+				"WHERE fk.schema_id = SCHEMA_ID(?) OR fk.referenced_object_id IN (SELECT object_id FROM sys.tables WHERE schema_id = SCHEMA_ID(?))",
+			name, name,
+		).Scan(&constraints).Error; err != nil {
+			return err
+		}
+		for _, c := range constraints {
+			if err := tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?", clause.Table{Name: c.Parent}, clause.Column{Name: c.Name}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, objectType := range schemaObjectTypes {
+			var objects []string
+			if err := tx.Raw(
+				"SELECT name FROM sys.objects WHERE schema_id = SCHEMA_ID(?) AND parent_object_id = 0 AND type IN ?",
+				name, objectType.types,
+			).Scan(&objects).Error; err != nil {
+				return err
+			}
+			for _, object := range objects {
+				if err := tx.Exec(objectType.drop, clause.Table{Name: name + "." + object}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var types []string
+		if err := tx.Raw("SELECT name FROM sys.types WHERE schema_id = SCHEMA_ID(?) AND is_user_defined = 1", name).Scan(&types).Error; err != nil {
+			return err
+		}
+		for _, typ := range types {
+			if err := tx.Exec("DROP TYPE ?", clause.Table{Name: name + "." + typ}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec("DROP SCHEMA ?", clause.Column{Name: name}).Error
+	})
+}
+
+// createMissingSchema creates the schema of value's table if it does not exist
+func (m Migrator) createMissingSchema(value interface{}) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if schemaName, _ := m.tableNameOf(stmt); schemaName != "" && !m.HasSchema(schemaName) {
+			return m.CreateSchema(schemaName)
+		}
+		return nil
+	})
+}
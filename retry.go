@@ -0,0 +1,149 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/microsoft/go-mssqldb"
+
+	"gorm.io/gorm"
+)
+
+// The error numbers of transient faults, which may succeed when retried, they include the Azure SQL transient errors listed in
+// https://learn.microsoft.com/en-us/azure/azure-sql/database/troubleshoot-common-errors-issues
+var transientErrorNumbers = map[int32]bool{
+	1205:  true, // deadlock victim
+	3960:  true, // snapshot isolation update conflict
+	233:   true, // connection closed by the server
+	4060:  true, // cannot open database
+	4221:  true, // login to read-secondary failed
+	10053: true, // connection aborted
+	10054: true, // connection reset
+	10060: true, // connection timed out
+	10928: true, // resource limit reached
+	10929: true, // resource limit reached
+	40143: true, // connection could not be initialized
+	40197: true, // service error processing the request
+	40501: true, // service is busy
+	40540: true, // service encountered an error
+	40613: true, // database is not currently available
+	49918: true, // not enough resources to process the request
+	49919: true, // too many create or update operations in progress
+	49920: true, // too many operations in progress
+}
+
+// IsTransientError reports whether err is a transient SQL Server fault, like a deadlock, a snapshot conflict,
+// an Azure SQL transient error or a broken connection
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var mssqlErr mssql.Error
+	if errors.As(err, &mssqlErr) {
+		if transientErrorNumbers[mssqlErr.Number] {
+			return true
+		}
+		for _, batchErr := range mssqlErr.All {
+			if transientErrorNumbers[batchErr.Number] {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, ErrDeadlock) || errors.Is(err, ErrSnapshotConflict) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryPolicy retries transactions and idempotent statements failing with transient errors,
+// waiting an exponential backoff with full jitter between the attempts
+type RetryPolicy struct {
+	// MaxRetries is the max number of retries after the first attempt, defaults to 3 if nil, 0 disables retrying
+	MaxRetries *int
+	// BaseDelay is the backoff of the first retry, which doubles with every retry, defaults to 100ms
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, defaults to 5s
+	MaxDelay time.Duration
+	// IsTransient classifies errors as retryable, defaults to IsTransientError
+	IsTransient func(err error) bool
+	// OnRetry is called before waiting for a retry, e.g. for metrics and logging
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// Transaction runs fc in a transaction, the whole transaction is rolled back and retried on transient errors.
+// Nested transactions aren't retried, SQL Server rolls back the outer transaction on errors like deadlocks,
+// so it's up to the outer transaction to retry.
+func (policy RetryPolicy) Transaction(db *gorm.DB, fc func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return db.Transaction(fc, opts...)
+	}
+	return policy.Do(db.Statement.Context, func() error {
+		return db.Transaction(fc, opts...)
+	})
+}
+
+// Do runs fc and retries it on transient errors, only use it for statements known to be idempotent,
+// or for work that is rolled back on failure as a whole
+func (policy RetryPolicy) Do(ctx context.Context, fc func() error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	isTransient := policy.IsTransient
+	if isTransient == nil {
+		isTransient = IsTransientError
+	}
+
+	maxRetries := 3
+	if policy.MaxRetries != nil {
+		maxRetries = *policy.MaxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err = fc(); err == nil || attempt >= maxRetries || !isTransient(err) {
+			return err
+		}
+
+		delay := policy.backoff(attempt)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt+1, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random delay up to BaseDelay * 2^attempt, capped by MaxDelay
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	baseDelay, maxDelay := policy.BaseDelay, policy.MaxDelay
+	if baseDelay <= 0 {
+		baseDelay = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Second
+	}
+
+	delay := maxDelay
+	if attempt < 32 && baseDelay<<uint(attempt) > 0 && baseDelay<<uint(attempt) < maxDelay {
+		delay = baseDelay << uint(attempt)
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
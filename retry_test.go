@@ -0,0 +1,111 @@
+package sqlserver_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/microsoft/go-mssqldb"
+
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "deadlock", err: mssql.Error{Number: 1205}, want: true},
+		{name: "azure database unavailable", err: fmt.Errorf("query: %w", mssql.Error{Number: 40613}), want: true},
+		{name: "translated snapshot conflict", err: sqlserver.Dialector{}.Translate(mssql.Error{Number: 3960}), want: true},
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "duplicated key", err: mssql.Error{Number: 2627}, want: false},
+		{name: "record not found", err: gorm.ErrRecordNotFound, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlserver.IsTransientError(tt.err); got != tt.want {
+				t.Errorf("IsTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	var (
+		retries    []int
+		maxRetries = 2
+	)
+	policy := sqlserver.RetryPolicy{
+		MaxRetries: &maxRetries,
+		BaseDelay:  time.Millisecond,
+		OnRetry: func(attempt int, delay time.Duration, err error) {
+			retries = append(retries, attempt)
+		},
+	}
+
+	attempts := 0
+	err := policy.Do(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return mssql.Error{Number: 1205}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 || len(retries) != 2 {
+		t.Errorf("expected success after 3 attempts and 2 retries, got error %v, %d attempts, retries %v", err, attempts, retries)
+	}
+
+	attempts = 0
+	err = policy.Do(context.Background(), func() error {
+		attempts++
+		return mssql.Error{Number: 1205}
+	})
+	if !errors.As(err, &mssql.Error{}) || attempts != 3 {
+		t.Errorf("expected deadlock error after 3 attempts, got error %v, %d attempts", err, attempts)
+	}
+
+	attempts = 0
+	err = policy.Do(context.Background(), func() error {
+		attempts++
+		return mssql.Error{Number: 2627}
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected no retry of non transient error, got error %v, %d attempts", err, attempts)
+	}
+	noRetries := 0
+	policy.MaxRetries = &noRetries
+	attempts = 0
+	err = policy.Do(context.Background(), func() error {
+		attempts++
+		return mssql.Error{Number: 1205}
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected no retry with MaxRetries 0, got error %v, %d attempts", err, attempts)
+	}
+}
+
+func TestRetryPolicy_NestedTransaction(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open(sqlserverDSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// the outer transaction is rolled back by the deadlock, the nested one can't be retried
+		return sqlserver.RetryPolicy{BaseDelay: time.Millisecond}.Transaction(tx, func(tx *gorm.DB) error {
+			attempts++
+			return mssql.Error{Number: 1205}
+		})
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("expected nested transaction not to be retried, got error %v, %d attempts", err, attempts)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	_ "github.com/microsoft/go-mssqldb"
 	"gorm.io/gorm"
@@ -21,6 +22,28 @@ type Config struct {
 	DSN               string
 	DefaultStringSize int
 	Conn              gorm.ConnPool
+	// MergeHoldLock adds WITH (HOLDLOCK) to the target of every MERGE upsert
+	MergeHoldLock bool
+	// OutputIntoTableVariable returns values generated on create through a table variable (or SCOPE_IDENTITY()
+	// for single rows) instead of a plain OUTPUT clause, which is rejected for tables with enabled triggers
+	OutputIntoTableVariable bool
+	// DetectTriggers looks up enabled triggers of a table on its first create and handles it like OutputIntoTableVariable if there are any,
+	// the result is cached per table until Dialector.ResetTriggerCache is called, e.g. after creating triggers at runtime
+	DetectTriggers bool
+	// DowngradeCascadePaths creates foreign keys whose cascading actions would cause cycles or multiple cascade paths
+	// with NO ACTION and logs a warning, instead of failing the migration with ErrCascadePaths
+	DowngradeCascadePaths bool
+	// ErrorTranslations maps additional error numbers, e.g. of custom RAISERROR messages, to errors returned by Translate
+	ErrorTranslations map[int32]error
+	// MigratorDefaultSchema is the schema the migrator creates and alters tables without an explicit schema in,
+	// defaults to the default schema of the database user. It only applies to the migrator, unqualified table names of
+	// queries, creates, updates and deletes still resolve to the default schema of the database user, so it has to
+	// match that schema, or the models have to qualify their table names, e.g. with NamingStrategy.TablePrefix.
+	MigratorDefaultSchema string
+	// AutoCreateSchema creates missing schemas of tables when creating them
+	AutoCreateSchema bool
+
+	triggerTables *sync.Map
 }
 
 type Dialector struct {
@@ -39,7 +62,29 @@ func New(config Config) gorm.Dialector {
 	return &Dialector{Config: &config}
 }
 
+// configOf returns the dialector config of db, or an empty config if it is not a sqlserver dialector
+func configOf(db *gorm.DB) *Config {
+	var config *Config
+	switch dialector := db.Dialector.(type) {
+	case *Dialector:
+		config = dialector.Config
+	case Dialector:
+		config = dialector.Config
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	return config
+}
+
 func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
+	if dialector.DriverName == "" {
+		dialector.DriverName = "sqlserver"
+	}
+	if dialector.Config.triggerTables == nil {
+		dialector.Config.triggerTables = &sync.Map{}
+	}
+
 	// register callbacks
 	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{
 		CreateClauses: []string{"INSERT", "VALUES", "ON CONFLICT"},
@@ -50,10 +95,6 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 	db.Callback().Create().Replace("gorm:create", Create)
 	db.Callback().Update().Replace("gorm:update", Update)
 
-	if dialector.DriverName == "" {
-		dialector.DriverName = "sqlserver"
-	}
-
 	if dialector.Conn != nil {
 		db.ConnPool = dialector.Conn
 	} else {
@@ -181,7 +222,17 @@ func (dialector Dialector) Explain(sql string, vars ...interface{}) string {
 	return logger.ExplainSQL(sql, numericPlaceholder, `'`, vars...)
 }
 
+// DataTypeOf returns the data type of field, followed by COLLATE for character fields with a collation,
+// invalid collations are rejected by the migrator
 func (dialector Dialector) DataTypeOf(field *schema.Field) string {
+	dataType := dialector.dataTypeOf(field)
+	if collation, err := collationOf(field); err == nil && collation != "" && isCharacterType(dataType) {
+		dataType += " COLLATE " + collation
+	}
+	return dataType
+}
+
+func (dialector Dialector) dataTypeOf(field *schema.Field) string {
 	switch field.DataType {
 	case schema.Bool:
 		return "bit"
@@ -197,7 +248,9 @@ func (dialector Dialector) DataTypeOf(field *schema.Field) string {
 		}
 
 		if field.AutoIncrement {
-			return sqlType + " IDENTITY(1,1)"
+			// invalid seeds are rejected by the migrator
+			seed, increment, _ := identityOf(field)
+			return fmt.Sprintf("%s IDENTITY(%d,%d)", sqlType, seed, increment)
 		}
 		return sqlType
 	case schema.Float:
@@ -0,0 +1,153 @@
+package sqlserver
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// truncation is a table emptied by TruncateTable, with TRUNCATE TABLE after dropping the foreign keys referencing it,
+// or with DELETE if tables that aren't emptied reference it
+type truncation struct {
+	table        string
+	foreignKeys  []ColumnDependency
+	referencedBy map[string]bool
+	delete       bool
+}
+
+// TruncateTable empties the tables of values and resets their identities. Tables are truncated with TRUNCATE TABLE,
+// the foreign keys referencing them from the emptied tables are dropped and recreated afterwards. Tables referenced
+// by other tables are emptied with DELETE instead, which fails if rows of the other tables reference them, and their
+// identities are reseeded with DBCC CHECKIDENT.
+func (m Migrator) TruncateTable(values ...interface{}) error {
+	var (
+		truncations = make([]truncation, 0, len(values))
+		emptied     = map[string]bool{}
+	)
+	for _, value := range values {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			table := m.fullTableNameOf(stmt)
+			if !emptied[strings.ToLower(table)] {
+				emptied[strings.ToLower(table)] = true
+				truncations = append(truncations, truncation{table: table})
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	var inTransaction bool
+	for idx := range truncations {
+		foreignKeys, err := m.referencingForeignKeysOf(truncations[idx].table)
+		if err != nil {
+			return err
+		}
+		truncations[idx].referencedBy = map[string]bool{}
+		for _, foreignKey := range foreignKeys {
+			truncations[idx].referencedBy[strings.ToLower(foreignKey.Table)] = true
+			truncations[idx].delete = truncations[idx].delete || !emptied[strings.ToLower(foreignKey.Table)]
+		}
+		if !truncations[idx].delete {
+			truncations[idx].foreignKeys = foreignKeys
+		}
+		inTransaction = inTransaction || len(foreignKeys) > 0
+	}
+
+	// the dropped foreign keys have to be recreated even if emptying a table fails,
+	// dry runs only log the statements, they don't begin a transaction on the database
+	if inTransaction && !m.DB.DryRun {
+		return m.DB.Transaction(func(tx *gorm.DB) error {
+			m := m
+			m.DB = tx
+			return m.truncate(truncations)
+		})
+	}
+	return m.truncate(truncations)
+}
+
+func (m Migrator) truncate(truncations []truncation) error {
+	var foreignKeys []ColumnDependency
+	for _, truncation := range truncations {
+		foreignKeys = append(foreignKeys, truncation.foreignKeys...)
+	}
+	if err := m.dropColumnDependencies(foreignKeys); err != nil {
+		return err
+	}
+
+	emptied := map[string]bool{}
+	for _, truncation := range truncations {
+		if !truncation.delete {
+			if err := m.DB.Exec("TRUNCATE TABLE ?", clause.Table{Name: truncation.table}).Error; err != nil {
+				return err
+			}
+			emptied[strings.ToLower(truncation.table)] = true
+		}
+	}
+
+	// delete the rows of referencing tables first, as far as they are known
+	for len(emptied) < len(truncations) {
+		for _, truncation := range deletableTruncations(truncations, emptied) {
+			if err := m.DB.Exec("DELETE FROM ?", clause.Table{Name: truncation.table}).Error; err != nil {
+				return err
+			}
+			if err := m.reseedIdentity(truncation.table); err != nil {
+				return err
+			}
+			emptied[strings.ToLower(truncation.table)] = true
+		}
+	}
+
+	return m.recreateColumnDependencies(nil, foreignKeys)
+}
+
+// deletableTruncations returns the tables to empty with DELETE which aren't referenced by the remaining tables,
+// or all remaining tables if they reference each other
+func deletableTruncations(truncations []truncation, emptied map[string]bool) (deletable []truncation) {
+	var remaining []truncation
+	for _, truncation := range truncations {
+		if !emptied[strings.ToLower(truncation.table)] {
+			remaining = append(remaining, truncation)
+		}
+	}
+
+	for _, truncation := range remaining {
+		referenced := false
+		for _, other := range remaining {
+			if !strings.EqualFold(other.table, truncation.table) && truncation.referencedBy[strings.ToLower(other.table)] {
+				referenced = true
+				break
+			}
+		}
+		if !referenced {
+			deletable = append(deletable, truncation)
+		}
+	}
+	if len(deletable) == 0 {
+		return remaining
+	}
+	return deletable
+}
+
+// referencingForeignKeysOf returns the foreign keys referencing the table with their definitions
+func (m Migrator) referencingForeignKeysOf(table string) ([]ColumnDependency, error) {
+	foreignKeys, err := m.queryForeignKeys("fk.referenced_object_id = OBJECT_ID(?)", table)
+	for idx := range foreignKeys {
+		foreignKeys[idx].Recreate = true
+	}
+	return foreignKeys, err
+}
+
+// reseedIdentity resets the identity of the emptied table, so the next row gets the seed value again
+func (m Migrator) reseedIdentity(table string) error {
+	var reseed []int64
+	// tables without rows since their creation start with the seed value already
+	if err := m.queryDB().Raw(
+		"SELECT CAST(seed_value AS bigint) - CAST(increment_value AS bigint) FROM sys.identity_columns WHERE object_id = OBJECT_ID(?) AND last_value IS NOT NULL",
+		table,
+	).Scan(&reseed).Error; err != nil || len(reseed) == 0 {
+		return err
+	}
+	return m.checkIdent(table, reseed[0])
+}
@@ -0,0 +1,129 @@
+package sqlserver_test
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gorm.io/driver/sqlserver"
+)
+
+func TestMigrator_TruncateTableDryRun(t *testing.T) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := openDryRunDB(t, sqlserver.Config{MigratorDefaultSchema: "dbo"}).Session(&gorm.Session{Logger: recorder})
+
+	if err := db.Migrator().(sqlserver.Migrator).TruncateTable(&testCascadeCustomer{}, &testCascadeAccount{}, &testCascadeCustomer{}); err != nil {
+		t.Fatalf("failed to truncate tables, got error: %v", err)
+	}
+	// the foreign keys and identities are queried in dry runs too, only the truncations are dry run
+	var statements []string
+	for _, sql := range recorder.sqls {
+		if !strings.HasPrefix(sql, "SELECT") {
+			statements = append(statements, sql)
+		}
+	}
+	want := []string{`TRUNCATE TABLE "dbo"."test_cascade_customers"`, `TRUNCATE TABLE "dbo"."test_cascade_accounts"`}
+	if len(statements) != len(want) || statements[0] != want[0] || statements[1] != want[1] {
+		t.Errorf("expected SQL %v, got %v", want, statements)
+	}
+}
+
+type TestTableTruncateParent struct {
+	ID   uint
+	Name string
+}
+
+func (*TestTableTruncateParent) TableName() string { return "test_table_truncate_parent" }
+
+type TestTableTruncateChild struct {
+	ID       uint
+	ParentID uint
+	Parent   TestTableTruncateParent
+}
+
+func (*TestTableTruncateChild) TableName() string { return "test_table_truncate_child" }
+
+func TestMigrator_TruncateTable(t *testing.T) {
+	db, err := gorm.Open(sqlserver.Open(sqlserverDSN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.Debug()
+	dm := db.Migrator().(sqlserver.Migrator)
+	defer func() {
+		if err = dm.DropTable(&TestTableTruncateChild{}, &TestTableTruncateParent{}); err != nil {
+			t.Errorf("couldn't drop tables, got error: %v", err)
+		}
+	}()
+	if err = dm.AutoMigrate(&TestTableTruncateParent{}, &TestTableTruncateChild{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fill := func() {
+		parent := TestTableTruncateParent{Name: "parent"}
+		if err := db.Create(&parent).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&TestTableTruncateChild{ParentID: parent.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	firstID := func(value interface{}) (id uint) {
+		if err := db.Model(value).Select("id").Order("id").Limit(1).Scan(&id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// the foreign key referencing the parent is dropped to truncate both tables
+	fill()
+	fill()
+	if err = dm.TruncateTable(&TestTableTruncateParent{}, &TestTableTruncateChild{}); err != nil {
+		t.Fatal(err)
+	}
+	if !dm.HasConstraint(&TestTableTruncateChild{}, "Parent") {
+		t.Errorf("expected foreign key to be recreated")
+	}
+	fill()
+	if id := firstID(&TestTableTruncateParent{}); id != 1 {
+		t.Errorf("expected identity to restart at 1, got %d", id)
+	}
+
+	// the parent referenced by a table that isn't emptied is deleted, which fails for referenced rows
+	if err = dm.TruncateTable(&TestTableTruncateParent{}); err == nil {
+		t.Errorf("expected error for deleting referenced rows")
+	}
+	if err = dm.TruncateTable(&TestTableTruncateChild{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.TruncateTable(&TestTableTruncateParent{}); err != nil {
+		t.Fatal(err)
+	}
+	fill()
+	if id := firstID(&TestTableTruncateParent{}); id != 1 {
+		t.Errorf("expected identity to be reseeded to 1, got %d", id)
+	}
+
+	// a disabled NOT FOR REPLICATION foreign key is recreated as it was
+	if err = dm.DropConstraint(&TestTableTruncateChild{}, "Parent"); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Exec("ALTER TABLE test_table_truncate_child ADD CONSTRAINT fk_test_table_truncate_child_parent FOREIGN KEY (parent_id) REFERENCES test_table_truncate_parent (id) NOT FOR REPLICATION").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.DisableConstraints(&TestTableTruncateChild{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = dm.TruncateTable(&TestTableTruncateParent{}, &TestTableTruncateChild{}); err != nil {
+		t.Fatal(err)
+	}
+	constraints, err := dm.GetConstraints(&TestTableTruncateChild{})
+	if err != nil || len(constraints) != 1 {
+		t.Fatalf("expected the foreign key, got %+v, error: %v", constraints, err)
+	}
+	if !constraints[0].Disabled || !constraints[0].NotForReplication {
+		t.Errorf("expected disabled NOT FOR REPLICATION foreign key, got %+v", constraints[0])
+	}
+}
@@ -14,5 +14,7 @@ func Update(db *gorm.DB) {
 		db.Statement.Omits = append(db.Statement.Omits, db.Statement.Schema.PrioritizedPrimaryField.DBName)
 	}
 
-	updateFunc(db)
+	withoutComputedColumns(db, func() {
+		updateFunc(db)
+	})
 }